@@ -1,22 +1,19 @@
 package daemon
 
 import (
-	"bytes"
 	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"regexp"
+	"strings"
 
-	"github.com/knadh/koanf"
-	"github.com/knadh/koanf/parsers/toml"
-	"github.com/knadh/koanf/providers/file"
 	"github.com/mdouchement/logger"
 	"github.com/mdouchement/shigoto/internal/config"
 	"github.com/mdouchement/shigoto/internal/cron"
 	"github.com/mdouchement/shigoto/internal/socket"
-	"github.com/pkg/errors"
+	"github.com/mdouchement/shigoto/pkg/history"
 	"github.com/spf13/cobra"
 )
 
@@ -31,19 +28,8 @@ var (
 		Short: "Start Shigoto service",
 		Args:  cobra.NoArgs,
 		RunE: func(c *cobra.Command, _ []string) error {
-			if cfg == "" {
-				var err error
-				cfg, err = config.Lookup(config.Filenames...)
-				if err != nil {
-					if err == os.ErrNotExist {
-						return errors.New("no configuration found from the default pathes")
-					}
-					return err
-				}
-			}
-
-			konf := koanf.New(".")
-			if err := konf.Load(file.Provider(cfg), toml.Parser()); err != nil {
+			konf, err := config.Load(cfg)
+			if err != nil {
 				return err
 			}
 
@@ -57,7 +43,39 @@ var (
 				TimestampFormat: "2006-01-02 15:04:05",
 			}))
 			log := logger.WrapSlog(l)
-			pool := cron.New(log)
+
+			options := []cron.Option{
+				cron.WithMaxConcurrentRuns(konf.Int("max_concurrent_runs")),
+				cron.WithLocks(konf.IntMap("locks")),
+				cron.WithGracePeriod(konf.Duration("grace_period")),
+			}
+			if directory := konf.String("history.directory"); directory != "" {
+				store, err := history.Open(directory, history.Retention{
+					MaxRecords: konf.Int("history.max_records"),
+					MaxAge:     konf.Duration("history.max_age"),
+				})
+				if err != nil {
+					return err
+				}
+				defer store.Close()
+
+				options = append(options, cron.WithHistory(store))
+			} else {
+				// The pauses are persisted next to the socket without history.
+				filename := konf.String("pause_file")
+				if filename == "" {
+					socket := konf.String("socket")
+					filename = strings.TrimSuffix(socket, filepath.Ext(socket)) + ".paused.json"
+				}
+				options = append(options, cron.WithPauseFile(filename))
+			}
+
+			pool := cron.New(log, options...)
+			handler := &handler{
+				directory: filepath.Join(konf.String("directory")),
+				pool:      pool,
+				log:       log,
+			}
 
 			//
 			//
@@ -66,21 +84,7 @@ var (
 			defer sock.Close()
 
 			go func() {
-				err := sock.Listen(func(event []byte) []byte {
-					if bytes.Equal(event, socket.SignalReload) {
-						log.Info("Reloading daemon")
-
-						err := cron.Load(filepath.Join(konf.String("directory")), pool, log)
-						if err != nil {
-							log.WithError(err).Error("Fail to reloading")
-							return []byte(err.Error())
-						}
-
-						log.Info("Reloaded")
-						return []byte("OK")
-					}
-					return []byte("Unsupported signal")
-				})
+				err := sock.Listen(handler.handle)
 				if err != nil {
 					fmt.Println(err)
 					os.Exit(1)
@@ -90,7 +94,7 @@ var (
 			//
 			//
 
-			err := cron.Load(filepath.Join(konf.String("directory")), pool, log)
+			err = cron.Load(handler.directory, pool, log)
 			if err != nil {
 				return err
 			}
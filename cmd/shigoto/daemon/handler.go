@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"github.com/mdouchement/logger"
+	"github.com/mdouchement/shigoto/internal/cron"
+	"github.com/mdouchement/shigoto/internal/socket"
+	"github.com/mdouchement/shigoto/pkg/history"
+)
+
+type handler struct {
+	directory string
+	pool      *cron.Pool
+	log       logger.Logger
+}
+
+func (h *handler) handle(event []byte) []byte {
+	switch {
+	case socket.SignalReload.Is(event):
+		h.log.Info("Reloading daemon")
+
+		err := cron.Load(h.directory, h.pool, h.log)
+		if err != nil {
+			h.log.WithError(err).Error("Fail to reloading")
+			return []byte(err.Error())
+		}
+
+		h.log.Info("Reloaded")
+		return []byte("OK")
+	case socket.SignalStatus.Is(event):
+		return socket.Reply(h.pool.Status(), nil)
+	case socket.SignalHistory.Is(event):
+		var q history.Query
+		if err := socket.Payload(event, &q); err != nil {
+			return socket.Reply(nil, err)
+		}
+
+		return socket.Reply(h.pool.History(q))
+	case socket.SignalTrigger.Is(event):
+		var t socket.Trigger
+		if err := socket.Payload(event, &t); err != nil {
+			return socket.Reply(nil, err)
+		}
+
+		return socket.Reply(h.pool.Trigger(t.Shigoto, t.Baito, t.Wait))
+	case socket.SignalPause.Is(event):
+		var t socket.Target
+		if err := socket.Payload(event, &t); err != nil {
+			return socket.Reply(nil, err)
+		}
+
+		return socket.Reply(nil, h.pool.Pause(t.Shigoto, t.Baito))
+	case socket.SignalResume.Is(event):
+		var t socket.Target
+		if err := socket.Payload(event, &t); err != nil {
+			return socket.Reply(nil, err)
+		}
+
+		return socket.Reply(nil, h.pool.Resume(t.Shigoto, t.Baito))
+	}
+
+	return []byte("Unsupported signal")
+}
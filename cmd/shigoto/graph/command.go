@@ -0,0 +1,44 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/mdouchement/shigoto/internal/config"
+	"github.com/mdouchement/shigoto/internal/cron"
+	"github.com/mdouchement/shigoto/pkg/shigoto"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	Command.Flags().StringVarP(&cfg, "config", "c", "", "Configuration file")
+}
+
+var (
+	// Command launches the graph subcommand.
+	Command = &cobra.Command{
+		Use:   "graph",
+		Short: "Print the dependencies between the baito of the shigoto files in DOT format",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			konf, err := config.Load(cfg)
+			if err != nil {
+				return err
+			}
+
+			shigotos, err := cron.LoadAll(konf.String("directory"))
+			if err != nil {
+				return err
+			}
+
+			graph := shigoto.NewGraph(shigotos...)
+			if err = graph.Validate(); err != nil {
+				return err
+			}
+
+			fmt.Print(graph.DOT())
+			return nil
+		},
+	}
+
+	cfg string
+)
@@ -0,0 +1,79 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mdouchement/shigoto/internal/config"
+	"github.com/mdouchement/shigoto/internal/socket"
+	"github.com/mdouchement/shigoto/pkg/history"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	Command.Flags().StringVarP(&cfg, "config", "c", "", "Configuration file")
+	Command.Flags().StringVarP(&output, "output", "o", "table", "Output format (table|json)")
+	Command.Flags().StringVarP(&query.Shigoto, "file", "f", "", "Shigoto file of the baito")
+	Command.Flags().IntVarP(&query.Limit, "limit", "n", 10, "Number of runs to show")
+	Command.Flags().BoolVar(&query.Failed, "failed", false, "Show only the failed runs")
+}
+
+var (
+	// Command launches the history subcommand.
+	Command = &cobra.Command{
+		Use:   "history baito",
+		Short: "Show the most recent runs of the given baito",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			konf, err := config.Load(cfg)
+			if err != nil {
+				return err
+			}
+
+			query.Baito = args[0]
+			if query.Shigoto != "" {
+				query.Shigoto = filepath.Base(query.Shigoto)
+			}
+			event, err := socket.SignalHistory.With(query)
+			if err != nil {
+				return err
+			}
+
+			var records []history.Record
+			err = socket.New(konf.String("socket")).Call(event, &records)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "json":
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(records)
+			case "table":
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "SHIGOTO\tBAITO\tSCHEDULED\tSTARTED\tDURATION\tERROR")
+				for _, r := range records {
+					scheduled := "-"
+					if !r.ScheduledTime.IsZero() {
+						scheduled = r.ScheduledTime.Format(time.DateTime)
+					}
+
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Shigoto, r.Baito, scheduled, r.StartTime.Format(time.DateTime), r.Duration.Round(time.Millisecond), r.Error)
+				}
+				return w.Flush()
+			}
+
+			return errors.Errorf("unsupported output format '%s'", output)
+		},
+	}
+
+	cfg    string
+	output string
+	query  history.Query
+)
@@ -6,8 +6,14 @@ import (
 	"runtime"
 
 	"github.com/mdouchement/shigoto/cmd/shigoto/daemon"
+	"github.com/mdouchement/shigoto/cmd/shigoto/graph"
+	"github.com/mdouchement/shigoto/cmd/shigoto/history"
+	"github.com/mdouchement/shigoto/cmd/shigoto/pause"
 	"github.com/mdouchement/shigoto/cmd/shigoto/reload"
+	"github.com/mdouchement/shigoto/cmd/shigoto/resume"
 	"github.com/mdouchement/shigoto/cmd/shigoto/run"
+	"github.com/mdouchement/shigoto/cmd/shigoto/status"
+	"github.com/mdouchement/shigoto/cmd/shigoto/trigger"
 	"github.com/mdouchement/shigoto/cmd/shigoto/validate"
 	"github.com/spf13/cobra"
 )
@@ -26,8 +32,14 @@ func main() {
 		Args:    cobra.NoArgs,
 	}
 	c.AddCommand(daemon.Command)
+	c.AddCommand(graph.Command)
+	c.AddCommand(history.Command)
+	c.AddCommand(pause.Command)
 	c.AddCommand(reload.Command)
+	c.AddCommand(resume.Command)
 	c.AddCommand(run.Command)
+	c.AddCommand(status.Command)
+	c.AddCommand(trigger.Command)
 	c.AddCommand(validate.Command)
 	c.AddCommand(&cobra.Command{
 		Use:   "version",
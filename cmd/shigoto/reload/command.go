@@ -2,14 +2,9 @@ package reload
 
 import (
 	"fmt"
-	"os"
 
-	"github.com/knadh/koanf"
-	"github.com/knadh/koanf/parsers/toml"
-	"github.com/knadh/koanf/providers/file"
 	"github.com/mdouchement/shigoto/internal/config"
 	"github.com/mdouchement/shigoto/internal/socket"
-	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -24,18 +19,8 @@ var (
 		Short: "Reload Shigoto service",
 		Args:  cobra.NoArgs,
 		RunE: func(c *cobra.Command, _ []string) (err error) {
-			if cfg == "" {
-				cfg, err = config.Lookup(config.Filenames...)
-				if err != nil {
-					if err == os.ErrNotExist {
-						return errors.New("no configuration found from the default pathes")
-					}
-					return err
-				}
-			}
-
-			konf := koanf.New(".")
-			if err := konf.Load(file.Provider(cfg), toml.Parser()); err != nil {
+			konf, err := config.Load(cfg)
+			if err != nil {
 				return err
 			}
 
@@ -0,0 +1,50 @@
+package resume
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/mdouchement/shigoto/internal/config"
+	"github.com/mdouchement/shigoto/internal/socket"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	Command.Flags().StringVarP(&cfg, "config", "c", "", "Configuration file")
+}
+
+var (
+	// Command launches the resume subcommand.
+	Command = &cobra.Command{
+		Use:   "resume file.yml [baito]",
+		Short: "Resume the given shigoto file or one of its baito in Shigoto service",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			konf, err := config.Load(cfg)
+			if err != nil {
+				return err
+			}
+
+			target := socket.Target{
+				Shigoto: filepath.Base(args[0]),
+			}
+			if len(args) > 1 {
+				target.Baito = args[1]
+			}
+
+			event, err := socket.SignalResume.With(target)
+			if err != nil {
+				return err
+			}
+
+			err = socket.New(konf.String("socket")).Call(event, nil)
+			if err != nil {
+				return err
+			}
+			fmt.Println("OK")
+			return nil
+		},
+	}
+
+	cfg string
+)
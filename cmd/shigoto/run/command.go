@@ -1,11 +1,14 @@
 package run
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
 	"regexp"
 	"slices"
+	"time"
 
 	"github.com/mdouchement/logger"
 	"github.com/mdouchement/shigoto/pkg/runner"
@@ -36,7 +39,7 @@ var Command = &cobra.Command{
 			return err
 		}
 
-		var selected []runner.Runner
+		var selected []string
 		for _, baito := range shigoto.Baito {
 			fmt.Println("Found baito:", baito.Name())
 
@@ -44,20 +47,40 @@ var Command = &cobra.Command{
 				continue
 			}
 
-			if args[1] == "ALL" {
-				selected = append(selected, baito.Commands()...)
-				continue
+			if args[1] == "ALL" || slices.Contains[[]string, string](args[1:], baito.Name()) {
+				selected = append(selected, baito.Name())
+			}
+		}
+		fmt.Println("---")
+
+		// Interrupting the run still runs the deferred commands.
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		ctx = logger.WithLogger(ctx, log)
+
+		for _, name := range selected {
+			if ctx.Err() != nil {
+				return runner.ErrInterrupted
 			}
 
-			if slices.Contains[[]string, string](args[1:], baito.Name()) {
-				selected = append(selected, baito.Commands()...)
+			baito := shigoto.Baito[name]
+			chain := runner.Timeout(runner.Chain(baito.Commands()...), baito.Timeout())
+			chain = runner.Retry(&baito, chain, baito.Retry())
+
+			now := time.Now()
+			result := chain.Run(runner.WithRun(ctx, runner.Run{
+				ID:            runner.GenerateID(),
+				Shigoto:       shigoto.Name,
+				Baito:         name,
+				ScheduledTime: now,
+				StartTime:     now,
+				Attempt:       1,
+			}))
+			if result.Error != nil {
+				return result.Error
 			}
 		}
-		fmt.Println("---")
 
-		chain := runner.Chain(selected...)
-		chain.AttachLogger(log)
-		chain.Run()
-		return chain.Error()
+		return nil
 	},
 }
@@ -0,0 +1,74 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mdouchement/shigoto/internal/config"
+	"github.com/mdouchement/shigoto/internal/cron"
+	"github.com/mdouchement/shigoto/internal/socket"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	Command.Flags().StringVarP(&cfg, "config", "c", "", "Configuration file")
+	Command.Flags().StringVarP(&output, "output", "o", "table", "Output format (table|json)")
+}
+
+var (
+	// Command launches the status subcommand.
+	Command = &cobra.Command{
+		Use:   "status",
+		Short: "Show the state of all the baito registered in Shigoto service",
+		Args:  cobra.NoArgs,
+		RunE: func(_ *cobra.Command, _ []string) error {
+			konf, err := config.Load(cfg)
+			if err != nil {
+				return err
+			}
+
+			var statuses []cron.Status
+			err = socket.New(konf.String("socket")).Call(socket.SignalStatus, &statuses)
+			if err != nil {
+				return err
+			}
+
+			switch output {
+			case "json":
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(statuses)
+			case "table":
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "SHIGOTO\tBAITO\tSCHEDULE\tPREVIOUS\tRESULT\tNEXT\tRUNNING\tPAUSED\tSKIPPED\tQUEUED")
+				for _, s := range statuses {
+					previous, result := "-", "-"
+					if s.Last != nil {
+						previous = s.Last.StartTime.Format(time.DateTime)
+						result = "OK"
+						if !s.Last.Succeeded() {
+							result = "FAILED"
+						}
+					}
+
+					next := "-"
+					if !s.Next.IsZero() {
+						next = s.Next.Format(time.DateTime)
+					}
+
+					fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%t\t%t\t%d\t%d\n", s.Shigoto, s.Baito, s.Schedule, previous, result, next, s.Running, s.Paused, s.Skipped, s.Queued)
+				}
+				return w.Flush()
+			}
+
+			return errors.Errorf("unsupported output format '%s'", output)
+		},
+	}
+
+	cfg    string
+	output string
+)
@@ -0,0 +1,106 @@
+package trigger
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mdouchement/shigoto/internal/config"
+	"github.com/mdouchement/shigoto/internal/socket"
+	"github.com/mdouchement/shigoto/pkg/history"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	Command.Flags().StringVarP(&cfg, "config", "c", "", "Configuration file")
+	Command.Flags().StringVarP(&output, "output", "o", "text", "Output format (text|json)")
+	Command.Flags().BoolVarP(&detach, "detach", "d", false, "Return immediately without waiting for the end of the run")
+}
+
+var (
+	// Command launches the trigger subcommand.
+	Command = &cobra.Command{
+		Use:   "trigger file.yml baito",
+		Short: "Run immediately the given baito in Shigoto service",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(_ *cobra.Command, args []string) error {
+			konf, err := config.Load(cfg)
+			if err != nil {
+				return err
+			}
+
+			event, err := socket.SignalTrigger.With(socket.Trigger{
+				Target: socket.Target{
+					Shigoto: filepath.Base(args[0]),
+					Baito:   args[1],
+				},
+				Wait: !detach,
+			})
+			if err != nil {
+				return err
+			}
+
+			var record *history.Record
+			err = socket.New(konf.String("socket")).Call(event, &record)
+			if err != nil {
+				return err
+			}
+
+			if record == nil {
+				fmt.Println("Triggered")
+				return nil
+			}
+
+			switch output {
+			case "json":
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err = encoder.Encode(record); err != nil {
+					return err
+				}
+			case "text":
+				result := "OK"
+				if !record.Succeeded() {
+					result = "FAILED"
+				}
+				if record.Attempts > 1 {
+					result = fmt.Sprintf("%s after %d attempts", result, record.Attempts)
+				}
+				fmt.Printf("%s - %s: %s in %s\n", record.Shigoto, record.Baito, result, record.Duration.Round(time.Millisecond))
+				for _, c := range record.Commands {
+					result := "OK"
+					switch {
+					case c.Skipped:
+						result = "SKIPPED"
+					case c.Error != "" && c.Ignored:
+						result = "IGNORED"
+					case c.Error != "":
+						result = "FAILED"
+					}
+					if c.Status != 0 {
+						result = fmt.Sprintf("%s (%d)", result, c.Status)
+					}
+					if c.ExitCode != 0 {
+						result = fmt.Sprintf("%s (exit %d)", result, c.ExitCode)
+					}
+
+					fmt.Printf("  #%d %s: %s in %s, %d bytes\n", c.Index, c.Label, result, c.Duration.Round(time.Millisecond), c.Output)
+				}
+			default:
+				return errors.Errorf("unsupported output format '%s'", output)
+			}
+
+			if !record.Succeeded() {
+				return errors.New(record.Error)
+			}
+			return nil
+		},
+	}
+
+	cfg    string
+	output string
+	detach bool
+)
@@ -2,8 +2,10 @@ package validate
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/mdouchement/shigoto/pkg/shigoto"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 )
 
@@ -13,7 +15,31 @@ var Command = &cobra.Command{
 	Short: "Validate given shigoto file",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(_ *cobra.Command, args []string) error {
-		if _, err := shigoto.Load(args[0]); err != nil {
+		s, err := shigoto.Load(args[0])
+		if err != nil {
+			return err
+		}
+
+		// The dependencies may target the other shigoto files of the directory.
+		shigotos := []*shigoto.Shigoto{s}
+		filenames, err := filepath.Glob(filepath.Join(filepath.Dir(args[0]), "*.yml"))
+		if err != nil {
+			return err
+		}
+		for _, filename := range filenames {
+			if filepath.Base(filename) == s.Name {
+				continue
+			}
+
+			// The daemon loads all the files of the directory, so an invalid sibling prevents the loading of this one.
+			sibling, err := shigoto.Load(filename)
+			if err != nil {
+				return errors.Wrap(err, filepath.Base(filename))
+			}
+			shigotos = append(shigotos, sibling)
+		}
+
+		if err = shigoto.NewGraph(shigotos...).Validate(); err != nil {
 			return err
 		}
 		fmt.Println("OK")
@@ -1,6 +1,13 @@
 package config
 
-import "os"
+import (
+	"os"
+
+	"github.com/knadh/koanf"
+	"github.com/knadh/koanf/parsers/toml"
+	"github.com/knadh/koanf/providers/file"
+	"github.com/pkg/errors"
+)
 
 // Filenames is the default configuration file pathes.
 var Filenames = []string{
@@ -25,3 +32,24 @@ func Lookup(filenames ...string) (string, error) {
 
 	return "", os.ErrNotExist
 }
+
+// Load loads the given configuration file.
+// When filename is empty, the first found default configuration file is loaded.
+func Load(filename string) (*koanf.Koanf, error) {
+	if filename == "" {
+		var err error
+		filename, err = Lookup(Filenames...)
+		if err != nil {
+			if err == os.ErrNotExist {
+				return nil, errors.New("no configuration found from the default pathes")
+			}
+			return nil, err
+		}
+	}
+
+	konf := koanf.New(".")
+	if err := konf.Load(file.Provider(filename), toml.Parser()); err != nil {
+		return nil, err
+	}
+	return konf, nil
+}
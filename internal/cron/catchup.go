@@ -0,0 +1,101 @@
+package cron
+
+import (
+	"time"
+
+	"github.com/mdouchement/shigoto/pkg/history"
+	"github.com/mdouchement/shigoto/pkg/shigoto"
+)
+
+// maxCatchUp is the maximum number of missed runs that are run by the catch-up.
+const maxCatchUp = 100
+
+// CatchUp runs the occurrences of the given shigoto's baito missed since their last successful run,
+// according to their catch-up policy. It requires the history.
+func (p *Pool) CatchUp(name string) {
+	if p.history == nil {
+		return
+	}
+
+	p.mu.Lock()
+	jobs := p.jobs[name]
+	p.mu.Unlock()
+
+	now := time.Now()
+	for _, job := range jobs {
+		baito := job.baito.Name()
+
+		if job.baito.CatchUp() == shigoto.CatchUpNone || job.baito.Schedule() == nil {
+			continue
+		}
+
+		last, ok, err := p.history.LastSuccess(name, baito)
+		if err != nil {
+			p.logger.WithError(err).Errorf(`Could not load the last success of "%s"`, baito)
+			continue
+		}
+		if !ok {
+			continue // Never run, nothing has been missed.
+		}
+
+		missed := missed(job.baito.Schedule(), last, now, job.baito.StartingDeadline())
+		if len(missed) == 0 {
+			continue
+		}
+
+		if job.baito.CatchUp() == shigoto.CatchUpOnce {
+			missed = missed[len(missed)-1:]
+		}
+
+		if p.pauses.contains(name, baito) {
+			p.logger.Infof(`Skipping catch-up of "%s" - paused`, baito)
+			continue
+		}
+
+		p.logger.Infof(`Catching up %d missed runs of "%s"`, len(missed), baito)
+		go job.catchUp(missed)
+	}
+}
+
+func (j *job) catchUp(missed []time.Time) {
+	for _, scheduled := range missed {
+		select {
+		case <-j.stop:
+			return // The remaining runs are dropped by the reload or the shutdown.
+		default:
+		}
+
+		if !j.acquire(scheduled) {
+			continue
+		}
+
+		j.run(scheduled, history.TriggerCatchUp)
+	}
+}
+
+// missed returns the last maxCatchUp activation times of the given schedule between last and now,
+// dropping the ones older than the given deadline.
+//
+// The activation times are walked from a window before now, doubled until it contains maxCatchUp times or reaches last,
+// so the times of a frequent schedule are not all walked since a last run long ago.
+func missed(schedule shigoto.Schedule, last, now time.Time, deadline time.Duration) []time.Time {
+	if deadline > 0 && last.Before(now.Add(-deadline)) {
+		last = now.Add(-deadline).Add(-time.Nanosecond) // Next is strictly after the given time.
+	}
+
+	for window := time.Minute; ; window *= 2 {
+		from := now.Add(-window)
+		if window <= 0 || !from.After(last) { // The window overflows or reaches last.
+			from = last
+		}
+
+		var times []time.Time
+		for t := schedule.Next(from); !t.IsZero() && !t.After(now); t = schedule.Next(t) {
+			times = append(times, t)
+		}
+
+		if len(times) >= maxCatchUp || from.Equal(last) {
+			return times[max(len(times)-maxCatchUp, 0):]
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+func TestMissed(t *testing.T) {
+	now := time.Date(2026, 10, 18, 13, 30, 0, 0, time.UTC)
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2026, 10, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		spec     string
+		last     time.Time
+		deadline time.Duration
+		count    int
+		first    time.Time
+		latest   time.Time
+	}{
+		{
+			name:  "no missed run",
+			spec:  "0 * * * *",
+			last:  at(18, 13, 0),
+			count: 0,
+		},
+		{
+			name:   "missed runs",
+			spec:   "0 * * * *",
+			last:   at(18, 10, 0),
+			count:  3,
+			first:  at(18, 11, 0),
+			latest: at(18, 13, 0),
+		},
+		{
+			name:   "missed runs before the window",
+			spec:   "0 * * * *",
+			last:   at(15, 13, 0),
+			count:  72,
+			first:  at(15, 14, 0),
+			latest: at(18, 13, 0),
+		},
+		{
+			name:   "missed runs over the cap",
+			spec:   "* * * * *",
+			last:   now.AddDate(-1, 0, 0),
+			count:  maxCatchUp,
+			first:  now.Add(-(maxCatchUp - 1) * time.Minute),
+			latest: now,
+		},
+		{
+			name:   "missed runs over the cap since the beginning of time",
+			spec:   "* * * * *",
+			last:   time.Time{},
+			count:  maxCatchUp,
+			first:  now.Add(-(maxCatchUp - 1) * time.Minute),
+			latest: now,
+		},
+		{
+			name:     "missed runs before the deadline",
+			spec:     "0 * * * *",
+			last:     at(17, 13, 0),
+			deadline: 140 * time.Minute,
+			count:    2,
+			first:    at(18, 12, 0),
+			latest:   at(18, 13, 0),
+		},
+		{
+			name:  "last success in the future",
+			spec:  "0 * * * *",
+			last:  now.Add(time.Hour),
+			count: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := cron.ParseStandard(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			times := missed(schedule, tt.last, now, tt.deadline)
+			if len(times) != tt.count {
+				t.Fatalf("got %d missed runs, expected %d", len(times), tt.count)
+			}
+			if tt.count == 0 {
+				return
+			}
+
+			if !times[0].Equal(tt.first) {
+				t.Errorf("first: got %s, expected %s", times[0], tt.first)
+			}
+			if latest := times[len(times)-1]; !latest.Equal(tt.latest) {
+				t.Errorf("latest: got %s, expected %s", latest, tt.latest)
+			}
+		})
+	}
+}
@@ -1,31 +1,118 @@
 package cron
 
 import (
+	"cmp"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/mdouchement/logger"
-	"github.com/mdouchement/shigoto/pkg/runner"
+	"github.com/mdouchement/shigoto/pkg/history"
 	"github.com/mdouchement/shigoto/pkg/shigoto"
+	"github.com/pkg/errors"
 	"github.com/robfig/cron/v3"
 )
 
-// A Pool contains a pool of crons and carries lots of helping methods.
-type Pool struct {
-	mu      sync.Mutex
-	logger  logger.Logger
-	running map[string]bool
-	cron    map[string]*cron.Cron
-	shigoto map[string]*shigoto.Shigoto
+type (
+	// A Pool contains a pool of crons and carries lots of helping methods.
+	Pool struct {
+		mu       sync.Mutex
+		logger   logger.Logger
+		history  *history.Store
+		running  map[string]bool
+		cron     map[string]*cron.Cron
+		jobs     map[string][]*job
+		shigoto  map[string]*shigoto.Shigoto
+		pauses   *pauses
+		limits   *limits
+		grace    time.Duration
+		draining map[*job]struct{} // The jobs of the reloaded shigoto until the end of their runs.
+	}
+
+	// An Option configures a Pool.
+	Option func(*Pool)
+
+	// A Status describes the state of a registered baito.
+	Status struct {
+		Shigoto  string          `json:"shigoto"`
+		Baito    string          `json:"baito"`
+		Schedule string          `json:"schedule"`
+		Running  bool            `json:"running"`
+		Paused   bool            `json:"paused"`
+		Skipped  int             `json:"skipped"`
+		Queued   int             `json:"queued"`
+		Next     time.Time       `json:"next"`
+		Last     *history.Record `json:"last,omitempty"`
+	}
+)
+
+var (
+	// ErrHistoryDisabled is returned when the history is requested on a Pool without store.
+	ErrHistoryDisabled = errors.New("history is disabled")
+	// ErrAlreadyRunning is returned when a baito is triggered while it is still running.
+	ErrAlreadyRunning = errors.New("already running")
+	// ErrStopped is returned when a baito is triggered while its shigoto is reloaded or the pool is stopped.
+	ErrStopped = errors.New("stopped, reloaded or shutting down")
+	// ErrDropped is returned when a baito is interrupted or timed out while waiting for its locks.
+	ErrDropped = errors.New("dropped while waiting for its locks")
+)
+
+// WithHistory records all the runs of the pool in the given store.
+func WithHistory(store *history.Store) Option {
+	return func(p *Pool) {
+		p.history = store
+	}
+}
+
+// WithGracePeriod defines how long the running baito of a reloaded shigoto or a stopped pool are given to finish before being interrupted.
+// A baito is given at least its timeout. The running baito are not interrupted with a zero grace period.
+func WithGracePeriod(grace time.Duration) Option {
+	return func(p *Pool) {
+		p.grace = grace
+	}
 }
 
 // New returns a new Pool.
-func New(l logger.Logger) *Pool {
-	return &Pool{
-		logger:  l,
-		running: make(map[string]bool),
-		cron:    make(map[string]*cron.Cron),
-		shigoto: make(map[string]*shigoto.Shigoto),
+func New(l logger.Logger, options ...Option) *Pool {
+	p := &Pool{
+		logger:   l,
+		running:  make(map[string]bool),
+		cron:     make(map[string]*cron.Cron),
+		jobs:     make(map[string][]*job),
+		shigoto:  make(map[string]*shigoto.Shigoto),
+		draining: make(map[*job]struct{}),
+		pauses: &pauses{
+			paused: make(map[[2]string]history.Pause),
+		},
+		limits: &limits{
+			sizes: make(map[string]int),
+			locks: make(map[string]*semaphore),
+		},
+	}
+
+	for _, option := range options {
+		option(p)
 	}
+
+	var paused []history.Pause
+	var err error
+	switch {
+	case p.history != nil:
+		paused, err = p.history.Paused()
+	case p.pauses.file != "":
+		paused, err = p.pauses.load()
+	default:
+		p.logger.Warn("The pauses will not survive the restarts without history or pause file")
+	}
+	if err != nil {
+		p.logger.WithError(err).Error("Could not load the paused baito")
+	}
+
+	for _, pause := range paused {
+		p.pauses.add(pause)
+	}
+
+	return p
 }
 
 // Register adds the given shigoto to the cron pool.
@@ -34,19 +121,45 @@ func (p *Pool) Register(s *shigoto.Shigoto) {
 	defer p.mu.Unlock()
 
 	p.shigoto[s.Name] = s
-	cron := cron.New(cron.WithLogger(
-		cron.PrintfLogger(p.logger)),
-		cron.WithChain(
-			cron.SkipIfStillRunning(cron.PrintfLogger(p.logger)),
-		),
-	)
+	cron := cron.New(cron.WithLogger(cron.PrintfLogger(p.logger)))
 	p.cron[s.Name] = cron
+	p.jobs[s.Name] = nil
 	for _, baito := range s.Baito {
-		chain := runner.Chain(baito.Commands()...)
-		chain.AttachLogger(p.logger)
-		cron.Schedule(baito.Schedule(), chain)
+		job := newJob(s.Name, baito)
+		job.logger = p.logger
+		job.history = p.history
+		job.pauses = p.pauses
+		job.limits = p.limits
+		job.notify = p.notify
+		for drained := range p.draining {
+			if drained.shigoto == s.Name && drained.baito.Name() == baito.Name() {
+				job.slot = drained.slot // The concurrency policy applies to the runs of the previous version.
+			}
+		}
+		if baito.Schedule() != nil {
+			job.schedule = &schedule{Schedule: baito.Schedule()}
+			job.id = cron.Schedule(job.schedule, job)
+		}
+		p.jobs[s.Name] = append(p.jobs[s.Name], job)
 
-		p.logger.Infof(`New job registered "%s" - "%s"`, baito.Name(), baito.Schedule())
+		if p.history != nil {
+			record, ok, err := p.history.Last(s.Name, baito.Name())
+			if err != nil {
+				p.logger.WithError(err).Errorf(`Could not load the history of "%s"`, baito.Name())
+			}
+			if ok {
+				job.last = &record
+			}
+		}
+
+		if baito.Schedule() != nil {
+			p.logger.Infof(`New job registered "%s" - "%s"`, baito.Name(), baito.Schedule())
+		} else {
+			p.logger.Infof(`New job registered "%s" - after %s`, baito.Name(), dependencies(baito.After()))
+		}
+		if p.pauses.contains(s.Name, baito.Name()) {
+			p.logger.Infof(`Job "%s" is paused`, baito.Name())
+		}
 	}
 }
 
@@ -58,6 +171,77 @@ func (p *Pool) Get(name string) *shigoto.Shigoto {
 	return p.shigoto[name]
 }
 
+// Status returns the status of all the registered baito.
+func (p *Pool) Status() []Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var statuses []Status
+	for name, jobs := range p.jobs {
+		for _, job := range jobs {
+			status := job.status()
+			if p.running[name] && job.schedule != nil {
+				status.Next = p.cron[name].Entry(job.id).Next
+			}
+
+			statuses = append(statuses, status)
+		}
+	}
+
+	slices.SortFunc(statuses, func(a, b Status) int {
+		return cmp.Or(cmp.Compare(a.Shigoto, b.Shigoto), cmp.Compare(a.Baito, b.Baito))
+	})
+	return statuses
+}
+
+// Trigger runs immediately the given baito of the given shigoto.
+// When wait is true, it waits for the end of the run and returns its record.
+func (p *Pool) Trigger(name, baito string, wait bool) (*history.Record, error) {
+	job, err := p.job(name, baito)
+	if err != nil {
+		return nil, err
+	}
+
+	p.logger.Infof(`Triggering "%s" - "%s"`, name, baito)
+	scheduled := time.Now()
+
+	if !wait && job.baito.Concurrency() != shigoto.ConcurrencySkip {
+		go func() {
+			if job.acquire(scheduled) {
+				job.run(scheduled, history.TriggerManual)
+			}
+		}()
+		return nil, nil
+	}
+
+	if !job.acquire(scheduled) {
+		if job.isStopped() {
+			return nil, ErrStopped
+		}
+		return nil, ErrAlreadyRunning
+	}
+
+	if !wait {
+		go job.run(scheduled, history.TriggerManual)
+		return nil, nil
+	}
+
+	record, ok := job.run(scheduled, history.TriggerManual)
+	if !ok {
+		return nil, ErrDropped
+	}
+	return &record, nil
+}
+
+// History returns the recorded runs matching the given query.
+func (p *Pool) History(q history.Query) ([]history.Record, error) {
+	if p.history == nil {
+		return nil, ErrHistoryDisabled
+	}
+
+	return p.history.Find(q)
+}
+
 // Start start all schedulers.
 func (p *Pool) Start() {
 	p.mu.Lock()
@@ -74,17 +258,36 @@ func (p *Pool) Start() {
 	}
 }
 
-// Stop stops and waits for the termination of the tasks of all schedulers.
+// Stop stops all schedulers and waits for the termination of their tasks, the reloaded ones included.
+// The running tasks are interrupted after the grace period (see WithGracePeriod).
 func (p *Pool) Stop() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	var jobs []*job
 	for name, cron := range p.cron {
 		p.logger.Infof("Shuting down the scheduler '%s'...", name)
-		<-cron.Stop().Done() // Wait for the termination of the tasks.
+		cron.Stop() // The scheduled runs in progress are acquired or refused by the retired jobs.
 
+		for _, job := range p.jobs[name] {
+			job.retire()
+			jobs = append(jobs, job)
+		}
 		delete(p.running, name)
 	}
+	for job := range p.draining {
+		jobs = append(jobs, job)
+	}
+
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.drain(p.grace)
+		}()
+	}
+	wg.Wait()
 }
 
 // StartShigoto starts the scheduler of the given shigoto's name.
@@ -101,15 +304,47 @@ func (p *Pool) StartShigoto(name string) {
 	p.running[name] = true
 }
 
-// StopShigoto stops and waits for the termination of the tasks of the given shigoto.
+// StopShigoto stops the scheduler of the given shigoto and unregisters it.
+// The running tasks are left to finish in the background and interrupted after the grace period (see WithGracePeriod).
 func (p *Pool) StopShigoto(name string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
 	p.logger.Infof("Shuting down the scheduler '%s'...", name)
-	<-p.cron[name].Stop().Done() // Wait for the termination of the tasks.
+	p.cron[name].Stop()
+	for _, job := range p.jobs[name] {
+		job.retire() // The scheduled runs in progress are acquired or refused.
+		p.draining[job] = struct{}{}
+
+		go func() {
+			job.drain(p.grace)
+
+			p.mu.Lock()
+			delete(p.draining, job)
+			p.mu.Unlock()
+		}()
+	}
 
 	delete(p.shigoto, name)
 	delete(p.cron, name)
+	delete(p.jobs, name)
 	delete(p.running, name)
 }
+
+func (p *Pool) job(name, baito string) (*job, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	jobs, ok := p.jobs[name]
+	if !ok {
+		return nil, errors.Errorf(`shigoto "%s" not found`, name)
+	}
+
+	for _, job := range jobs {
+		if job.baito.Name() == baito {
+			return job, nil
+		}
+	}
+
+	return nil, errors.Errorf(`baito "%s" not found in "%s"`, baito, name)
+}
@@ -0,0 +1,59 @@
+package cron
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mdouchement/shigoto/pkg/history"
+	"github.com/mdouchement/shigoto/pkg/shigoto"
+)
+
+// notify triggers the baito depending on the run of the given record.
+func (p *Pool) notify(record history.Record) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for name, jobs := range p.jobs {
+		if !p.running[name] {
+			continue
+		}
+
+		for _, job := range jobs {
+			for _, dependency := range job.baito.After() {
+				if dependency.Is(record.Shigoto, record.Baito) && dependency.On.Matches(record.Succeeded()) {
+					go job.follow(record)
+					break
+				}
+			}
+		}
+	}
+}
+
+// follow runs the job after the run of the given record.
+func (j *job) follow(record history.Record) {
+	upstream := path.Join(record.Shigoto, record.Baito)
+
+	if j.pauses.contains(j.shigoto, j.baito.Name()) {
+		j.logger.Infof(`Skipping "%s" triggered by "%s" - paused`, j.baito.Name(), upstream)
+		return
+	}
+
+	j.logger.Infof(`Triggering "%s" after "%s"`, j.baito.Name(), upstream)
+	scheduled := time.Now()
+
+	if !j.acquire(scheduled) {
+		return
+	}
+
+	j.run(scheduled, history.TriggerDependency)
+}
+
+// dependencies returns the given dependencies as a human readable list.
+func dependencies(after []shigoto.Dependency) string {
+	s := make([]string, 0, len(after))
+	for _, dependency := range after {
+		s = append(s, dependency.String())
+	}
+	return strings.Join(s, ", ")
+}
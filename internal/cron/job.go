@@ -0,0 +1,411 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mdouchement/logger"
+	"github.com/mdouchement/shigoto/pkg/history"
+	"github.com/mdouchement/shigoto/pkg/runner"
+	"github.com/mdouchement/shigoto/pkg/shigoto"
+	"github.com/robfig/cron/v3"
+)
+
+type (
+	// A job is a registered baito.
+	job struct {
+		mu          sync.Mutex
+		idle        *sync.Cond    // Signaled when the last acquired run ends.
+		stop        chan struct{} // Closed when the job is stopped.
+		id          cron.EntryID
+		shigoto     string
+		baito       shigoto.Baito
+		schedule    *schedule
+		logger      logger.Logger
+		history     *history.Store
+		pauses      *pauses
+		limits      *limits
+		notify      func(history.Record)
+		slot        chan struct{}
+		cancels     map[string]context.CancelFunc
+		stopped     bool // No more runs are acquired.
+		interrupted bool // The runs are cancelled.
+		inflight    int  // The number of acquired runs, queued ones included.
+		running     int
+		skipped     int
+		queued      int
+		last        *history.Record
+	}
+
+	// A schedule keeps track of the activation times computed by the scheduler.
+	schedule struct {
+		cron.Schedule
+		mu   sync.Mutex
+		prev time.Time
+		next time.Time
+	}
+)
+
+func (j *job) Run() {
+	scheduled := j.schedule.Activation(time.Now())
+
+	if j.pauses.contains(j.shigoto, j.baito.Name()) {
+		j.logger.Infof(`Skipping "%s" scheduled at %s - paused`, j.baito.Name(), scheduled.Format(time.DateTime))
+		return
+	}
+
+	if !j.acquire(scheduled) {
+		return
+	}
+
+	j.run(scheduled, history.TriggerSchedule)
+}
+
+// newJob returns a job running the given baito of the given shigoto.
+func newJob(name string, baito shigoto.Baito) *job {
+	j := &job{
+		shigoto: name,
+		baito:   baito,
+		stop:    make(chan struct{}),
+		slot:    make(chan struct{}, 1),
+		cancels: make(map[string]context.CancelFunc),
+	}
+	j.idle = sync.NewCond(&j.mu)
+	return j
+}
+
+// acquire reserves a run according to the concurrency policy of the baito.
+// It returns false if the run is skipped or the job is stopped and blocks while the run is queued.
+// An acquired run must be run.
+func (j *job) acquire(scheduled time.Time) bool {
+	j.mu.Lock()
+	if j.stopped {
+		j.mu.Unlock()
+		return false
+	}
+	j.inflight++
+	j.mu.Unlock()
+
+	if j.baito.Concurrency() != shigoto.ConcurrencyAllow {
+		select {
+		case j.slot <- struct{}{}:
+		default:
+			if !j.wait(scheduled) {
+				j.release(false)
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// isStopped returns true if the job does not acquire runs anymore.
+func (j *job) isStopped() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.stopped
+}
+
+// release ends an acquired run, freeing its slot if it holds it.
+func (j *job) release(slot bool) {
+	if slot && j.baito.Concurrency() != shigoto.ConcurrencyAllow {
+		<-j.slot
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.inflight--
+	if j.inflight == 0 {
+		j.idle.Broadcast()
+	}
+}
+
+// wait applies the concurrency policy of the baito while the previous run is still running.
+func (j *job) wait(scheduled time.Time) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	switch j.baito.Concurrency() {
+	case shigoto.ConcurrencyQueue:
+		j.queued++
+		j.logger.Infof(`Queuing "%s" scheduled at %s - still running (%d queued)`, j.baito.Name(), scheduled.Format(time.DateTime), j.queued)
+	case shigoto.ConcurrencyReplace:
+		j.logger.Infof(`Replacing the running "%s" by the one scheduled at %s`, j.baito.Name(), scheduled.Format(time.DateTime))
+		for _, cancel := range j.cancels {
+			cancel()
+		}
+	default:
+		j.skipped++
+		j.logger.Infof(`Skipping "%s" scheduled at %s - still running (%d skipped)`, j.baito.Name(), scheduled.Format(time.DateTime), j.skipped)
+		return false
+	}
+
+	j.mu.Unlock()
+	defer j.mu.Lock()
+
+	select {
+	case j.slot <- struct{}{}:
+		return true
+	case <-j.stop:
+		return false // The queued run is dropped.
+	}
+}
+
+// run runs the acquired job and returns the record of the run.
+// The run is dropped, without record, when it's interrupted or timed out while waiting for its locks.
+func (j *job) run(scheduled time.Time, trigger string) (history.Record, bool) {
+	chain := runner.Timeout(runner.Chain(j.baito.Commands()...), j.baito.Timeout())
+	chain = runner.Retry(&j.baito, chain, j.baito.Retry())
+	id := runner.GenerateID()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = logger.WithLogger(ctx, j.logger)
+
+	j.mu.Lock()
+	j.cancels[id] = cancel
+	if j.interrupted {
+		cancel()
+	}
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		delete(j.cancels, id)
+		j.mu.Unlock()
+		cancel()
+
+		j.release(true)
+	}()
+
+	release, err := j.lock(ctx)
+	if err != nil {
+		j.logger.WithError(err).Infof(`Dropping "%s" scheduled at %s`, j.baito.Name(), scheduled.Format(time.DateTime))
+		return history.Record{}, false
+	}
+	defer release()
+
+	j.mu.Lock()
+	j.running++
+	j.mu.Unlock()
+
+	defer func() {
+		j.mu.Lock()
+		j.running--
+		j.mu.Unlock()
+	}()
+
+	record := history.Record{
+		ID:            id,
+		Shigoto:       j.shigoto,
+		Baito:         j.baito.Name(),
+		Trigger:       trigger,
+		ScheduledTime: scheduled,
+		StartTime:     time.Now(),
+	}
+
+	result := chain.Run(runner.WithRun(ctx, runner.Run{
+		ID:            id,
+		Shigoto:       j.shigoto,
+		Baito:         j.baito.Name(),
+		ScheduledTime: scheduled,
+		StartTime:     record.StartTime,
+		Attempt:       1,
+	}))
+
+	record.Duration = time.Since(record.StartTime)
+	record.Attempts = result.Attempts
+	if result.Error != nil {
+		record.Error = result.Error.Error()
+	}
+	for _, result := range result.Results {
+		command := history.Command{
+			Index:     result.Index,
+			Label:     result.Label,
+			Deferred:  result.Deferred,
+			Ignored:   result.Ignored,
+			Skipped:   result.Skipped,
+			StartTime: result.StartTime,
+			Duration:  result.Duration(),
+			ExitCode:  result.ExitCode,
+			Status:    result.Status,
+			Output:    result.Output,
+			Attempts:  result.Attempts,
+		}
+		if result.Error != nil {
+			command.Error = result.Error.Error()
+		}
+
+		record.Commands = append(record.Commands, command)
+	}
+	if result.Error != nil {
+		logger := j.logger.WithError(result.Error)
+		if failure, ok := result.Failure(); ok && failure.Label != "" {
+			logger = logger.WithField("command", failure.Index).WithField("label", failure.Label)
+		}
+		logger.Errorf(`Run of "%s" failed`, j.baito.Name())
+	}
+
+	j.mu.Lock()
+	j.last = &record
+	j.mu.Unlock()
+
+	if j.history != nil {
+		j.save(record)
+	}
+
+	if !errors.Is(ctx.Err(), context.Canceled) { // The dependents are not triggered by interrupted runs.
+		go j.notify(record) // The pool may be locked while it waits for the end of the run.
+	}
+	return record, true
+}
+
+// lock waits for the limits of the pool and the locks of the baito.
+// The wait is bounded by the timeout of the baito and interrupted with the run.
+func (j *job) lock(ctx context.Context) (release func(), err error) {
+	if timeout := j.baito.Timeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, timeout, fmt.Errorf("timed out after %s", timeout))
+		defer cancel()
+	}
+
+	release, err = j.limits.acquire(ctx, j.baito.Locks(), j.logger.WithPrefixf("[%s]", j.baito.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	if ctx.Err() != nil { // Interrupted while the last lock was acquired.
+		release()
+		return nil, context.Cause(ctx)
+	}
+	return release, nil
+}
+
+// save persists the given record in the history.
+func (j *job) save(record history.Record) {
+	if err := j.history.Save(record); err != nil {
+		j.logger.WithError(err).Errorf(`Could not save the history of "%s"`, j.baito.Name())
+	}
+	if record.Succeeded() {
+		if err := j.history.Succeeded(j.shigoto, j.baito.Name(), record.ScheduledTime); err != nil {
+			j.logger.WithError(err).Errorf(`Could not save the last success of "%s"`, j.baito.Name())
+		}
+	}
+}
+
+// retire stops the acquisition of new runs, the queued runs are dropped and the acquired ones go on.
+func (j *job) retire() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.stopped {
+		j.stopped = true
+		close(j.stop)
+	}
+}
+
+// cancel stops the job and interrupts the current runs and the ones starting from now.
+func (j *job) cancel() {
+	j.retire()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.interrupted = true
+	for _, cancel := range j.cancels {
+		cancel()
+	}
+}
+
+// drain waits for the end of the acquired runs of the retired job.
+// They are interrupted after the given grace period, or the timeout of the baito when it's longer.
+// They are not interrupted with a zero grace period.
+func (j *job) drain(grace time.Duration) {
+	done := make(chan struct{})
+	go func() {
+		j.done()
+		close(done)
+	}()
+
+	if grace <= 0 {
+		<-done
+		return
+	}
+
+	timer := time.NewTimer(max(grace, j.baito.Timeout()))
+	defer timer.Stop()
+
+	select {
+	case <-done:
+	case <-timer.C:
+		j.logger.Infof(`Interrupting "%s" - grace period elapsed`, j.baito.Name())
+		j.cancel()
+		<-done
+	}
+}
+
+// done waits for the end of the acquired runs.
+func (j *job) done() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for j.inflight > 0 {
+		j.idle.Wait()
+	}
+}
+
+// status returns the status of the job without the next activation.
+func (j *job) status() Status {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	schedule := "after " + dependencies(j.baito.After())
+	if j.schedule != nil {
+		schedule = j.schedule.String()
+	}
+
+	return Status{
+		Shigoto:  j.shigoto,
+		Baito:    j.baito.Name(),
+		Schedule: schedule,
+		Running:  j.running > 0,
+		Skipped:  j.skipped,
+		Queued:   j.queued,
+		Paused:   j.pauses.contains(j.shigoto, j.baito.Name()),
+		Last:     j.last,
+	}
+}
+
+func (s *schedule) Next(t time.Time) time.Time {
+	next := s.Schedule.Next(t)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prev, s.next = s.next, next
+	return next
+}
+
+// Activation returns the activation time of a run started at the given time.
+// The scheduler may have already computed the following activation when the job starts.
+func (s *schedule) Activation(t time.Time) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next.After(t) {
+		return s.prev
+	}
+	return s.next
+}
+
+func (s *schedule) String() string {
+	if stringer, ok := s.Schedule.(interface{ String() string }); ok {
+		return stringer.String()
+	}
+	return ""
+}
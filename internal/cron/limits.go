@@ -0,0 +1,113 @@
+package cron
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/mdouchement/logger"
+	"github.com/pkg/errors"
+)
+
+type (
+	// limits bounds the number of concurrent runs across the whole pool.
+	limits struct {
+		mu     sync.Mutex
+		global *semaphore
+		sizes  map[string]int
+		locks  map[string]*semaphore
+	}
+
+	// A semaphore limits the number of concurrent holders. Waiting holders are served in order.
+	semaphore struct {
+		name  string
+		slots chan struct{}
+	}
+)
+
+// WithMaxConcurrentRuns limits the number of baito running at once in the pool.
+func WithMaxConcurrentRuns(n int) Option {
+	return func(p *Pool) {
+		if n > 0 {
+			p.limits.global = &semaphore{name: "max_concurrent_runs", slots: make(chan struct{}, n)}
+		}
+	}
+}
+
+// WithLocks defines the number of baito that can hold the named locks at once.
+// Undefined locks can be held by only one baito at once.
+func WithLocks(sizes map[string]int) Option {
+	return func(p *Pool) {
+		for name, n := range sizes {
+			p.limits.sizes[name] = n
+		}
+	}
+}
+
+// acquire waits for the given locks and the global limit until the context is done.
+// The locks are acquired in the same order by all the baito to avoid deadlocks,
+// the global limit being the last one so a baito waiting for a lock does not hold a slot of the pool.
+// Nothing is held when an error is returned.
+func (l *limits) acquire(ctx context.Context, names []string, log logger.Logger) (release func(), err error) {
+	var semaphores []*semaphore
+
+	names = slices.Clone(names)
+	slices.Sort(names)
+	for _, name := range slices.Compact(names) {
+		semaphores = append(semaphores, l.lock(name))
+	}
+
+	if l.global != nil {
+		semaphores = append(semaphores, l.global)
+	}
+
+	release = func() {}
+	for i, s := range semaphores {
+		if err := s.acquire(ctx, log); err != nil {
+			release()
+			return nil, err
+		}
+
+		acquired := semaphores[:i+1]
+		release = func() {
+			for _, s := range slices.Backward(acquired) {
+				s.release()
+			}
+		}
+	}
+
+	return release, nil
+}
+
+func (l *limits) lock(name string) *semaphore {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s, ok := l.locks[name]
+	if !ok {
+		n := max(l.sizes[name], 1)
+		s = &semaphore{name: name, slots: make(chan struct{}, n)}
+		l.locks[name] = s
+	}
+	return s
+}
+
+func (s *semaphore) acquire(ctx context.Context, log logger.Logger) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	log.Infof(`Waiting for "%s"`, s.name)
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrapf(context.Cause(ctx), `waiting for "%s"`, s.name)
+	}
+}
+
+func (s *semaphore) release() {
+	<-s.slots
+}
@@ -0,0 +1,64 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/logger"
+)
+
+// A waitLogger reports the semaphores a baito waits for.
+type waitLogger struct {
+	logger.Logger
+	waiting chan string
+}
+
+func (l *waitLogger) Infof(format string, args ...any) {
+	l.waiting <- fmt.Sprintf(format, args...)
+}
+
+func TestLimitsLockDoesNotHoldGlobalSlot(t *testing.T) {
+	p := New(logger.NewNullLogger(), WithMaxConcurrentRuns(2), WithLocks(map[string]int{"database": 1}))
+	log := &waitLogger{Logger: logger.NewNullLogger(), waiting: make(chan string, 1)}
+
+	// The holder of the lock runs with one of the two slots of the pool.
+	release, err := p.limits.acquire(context.Background(), []string{"database"}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	// The second baito waits for the lock.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+	go func() {
+		release, err := p.limits.acquire(ctx, []string{"database"}, log)
+		if err == nil {
+			release()
+		}
+		errs <- err
+	}()
+
+	if waiting := <-log.waiting; waiting != `Waiting for "database"` {
+		t.Fatalf("unexpected wait: %s", waiting)
+	}
+
+	// An unrelated baito runs with the remaining slot.
+	timeout, stop := context.WithTimeout(context.Background(), time.Second)
+	defer stop()
+
+	unrelated, err := p.limits.acquire(timeout, nil, log)
+	if err != nil {
+		t.Fatalf("unrelated baito starved: %v", err)
+	}
+	unrelated()
+
+	cancel()
+	if err := <-errs; err == nil {
+		t.Fatal("expected the waiting baito to be interrupted")
+	}
+}
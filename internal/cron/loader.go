@@ -10,18 +10,18 @@ import (
 
 // Load loads all shigoto files from the given workdir and registers it to the given pool and starts them.
 // It reload already registred shigoto if a change is detected.
+// Nothing is registered if the dependencies between the baito are invalid.
 func Load(workdir string, pool *Pool, log logger.Logger) error {
-	filenames, err := filepath.Glob(filepath.Join(workdir, "*.yml"))
+	shigotos, err := LoadAll(workdir)
 	if err != nil {
 		return err
 	}
 
-	for _, filename := range filenames {
-		shigoto, err := shigoto.Load(filename)
-		if err != nil {
-			return errors.Wrap(err, filepath.Base(filename))
-		}
+	if err = shigoto.NewGraph(shigotos...).Validate(); err != nil {
+		return err
+	}
 
+	for _, shigoto := range shigotos {
 		if len(shigoto.Baito) == 0 {
 			continue
 		}
@@ -30,6 +30,7 @@ func Load(workdir string, pool *Pool, log logger.Logger) error {
 		if registred == nil {
 			pool.Register(shigoto) // New Shigoto
 			pool.StartShigoto(shigoto.Name)
+			pool.CatchUp(shigoto.Name)
 			continue
 		}
 
@@ -46,3 +47,23 @@ func Load(workdir string, pool *Pool, log logger.Logger) error {
 
 	return nil
 }
+
+// LoadAll loads all shigoto files from the given workdir.
+func LoadAll(workdir string) ([]*shigoto.Shigoto, error) {
+	filenames, err := filepath.Glob(filepath.Join(workdir, "*.yml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var shigotos []*shigoto.Shigoto
+	for _, filename := range filenames {
+		shigoto, err := shigoto.Load(filename)
+		if err != nil {
+			return nil, errors.Wrap(err, filepath.Base(filename))
+		}
+
+		shigotos = append(shigotos, shigoto)
+	}
+
+	return shigotos, nil
+}
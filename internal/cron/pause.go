@@ -0,0 +1,170 @@
+package cron
+
+import (
+	"cmp"
+	"encoding/json"
+	"maps"
+	"os"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mdouchement/shigoto/pkg/history"
+	"github.com/pkg/errors"
+)
+
+// pauses holds the paused shigoto and baito.
+// It is shared by the pool and its jobs so it survives the reloads.
+type pauses struct {
+	mu     sync.Mutex
+	paused map[[2]string]history.Pause
+	file   string // Persists the pauses when the pool has no history.
+}
+
+// WithPauseFile persists the pauses in the given file when the pool has no history,
+// so they survive the restarts of the daemon.
+func WithPauseFile(filename string) Option {
+	return func(p *Pool) {
+		p.pauses.file = filename
+	}
+}
+
+// load loads the pauses persisted in the pause file.
+func (p *pauses) load() ([]history.Pause, error) {
+	b, err := os.ReadFile(p.file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var paused []history.Pause
+	err = json.Unmarshal(b, &paused)
+	return paused, errors.Wrap(err, p.file)
+}
+
+// save persists the pauses in the pause file.
+// The file is replaced at once so it's never partially written.
+func (p *pauses) save() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	paused := slices.SortedFunc(maps.Values(p.paused), func(a, b history.Pause) int {
+		return cmp.Or(cmp.Compare(a.Shigoto, b.Shigoto), cmp.Compare(a.Baito, b.Baito))
+	})
+
+	b, err := json.MarshalIndent(paused, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := p.file + ".tmp"
+	if err = os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.file)
+}
+
+func (p *pauses) add(pause history.Pause) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.paused[[2]string{pause.Shigoto, pause.Baito}] = pause
+}
+
+// remove removes the pause of the given baito or all the pauses of the given shigoto if baito is empty.
+func (p *pauses) remove(shigoto, baito string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for k := range p.paused {
+		if k[0] == shigoto && (baito == "" || k[1] == baito) {
+			delete(p.paused, k)
+		}
+	}
+}
+
+// contains returns true if the given baito or its shigoto is paused.
+func (p *pauses) contains(shigoto, baito string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, file := p.paused[[2]string{shigoto, ""}]
+	_, job := p.paused[[2]string{shigoto, baito}]
+	return file || job
+}
+
+// Pause pauses the given baito of the given shigoto.
+// An empty baito pauses all the baito of the shigoto.
+// Paused baito stay registered but their scheduled runs are skipped.
+func (p *Pool) Pause(name, baito string) error {
+	if err := p.exists(name, baito); err != nil {
+		return err
+	}
+
+	pause := history.Pause{
+		Shigoto: name,
+		Baito:   baito,
+		Time:    time.Now(),
+	}
+
+	if p.history != nil {
+		if err := p.history.Pause(pause); err != nil {
+			return err
+		}
+	}
+
+	p.pauses.add(pause)
+	if err := p.persist(); err != nil {
+		return err
+	}
+
+	p.logger.Infof(`Paused "%s"`, path.Join(name, baito))
+	return nil
+}
+
+// Resume resumes the given baito of the given shigoto.
+// An empty baito resumes the shigoto and all its baito.
+func (p *Pool) Resume(name, baito string) error {
+	if err := p.exists(name, baito); err != nil {
+		return err
+	}
+
+	if p.history != nil {
+		if err := p.history.Resume(name, baito); err != nil {
+			return err
+		}
+	}
+
+	p.pauses.remove(name, baito)
+	if err := p.persist(); err != nil {
+		return err
+	}
+
+	p.logger.Infof(`Resumed "%s"`, path.Join(name, baito))
+	return nil
+}
+
+// persist saves the pauses in the pause file when the pool has no history.
+// The pauses only last until the restart of the daemon when there is neither history nor pause file.
+func (p *Pool) persist() error {
+	if p.history != nil || p.pauses.file == "" {
+		return nil
+	}
+
+	return errors.Wrap(p.pauses.save(), "could not persist the pauses")
+}
+
+func (p *Pool) exists(name, baito string) error {
+	if baito != "" {
+		_, err := p.job(name, baito)
+		return err
+	}
+
+	if p.Get(name) == nil {
+		return errors.Errorf(`shigoto "%s" not found`, name)
+	}
+	return nil
+}
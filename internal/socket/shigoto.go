@@ -3,6 +3,7 @@ package socket
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"net"
 	"strings"
 
@@ -48,6 +49,27 @@ func (s *Socket) Request(event []byte) ([]byte, error) {
 	return bytes.Trim(payload, ControlCharacterString), nil
 }
 
+// Call requests the socket with the given event and decodes the Response data into v.
+func (s *Socket) Call(event []byte, v any) error {
+	payload, err := s.Request(event)
+	if err != nil {
+		return err
+	}
+
+	var response Response
+	if err = json.Unmarshal(payload, &response); err != nil {
+		return errors.Errorf("unexpected response: %s", payload)
+	}
+	if response.Error != "" {
+		return errors.New(response.Error)
+	}
+	if v == nil || len(response.Data) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(response.Data, v)
+}
+
 // Listen opens the socket and wait for incoming events.
 func (s *Socket) Listen(handler func(event []byte) []byte) error {
 	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: s.socket, Net: "unix"})
@@ -61,13 +83,20 @@ func (s *Socket) Listen(handler func(event []byte) []byte) error {
 		if err != nil {
 			return s.hide(err)
 		}
-		reader := bufio.NewReader(conn)
-		if event, err := reader.ReadBytes(ControlCharacter); err == nil {
-			event = bytes.Trim(event, ControlCharacterString)
 
-			payload := handler(event)
-			_, _ = conn.Write(append(payload, ControlCharacter))
-		}
+		go s.serve(conn, handler) // Some events wait for long tasks.
+	}
+}
+
+func (s *Socket) serve(conn net.Conn, handler func(event []byte) []byte) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	if event, err := reader.ReadBytes(ControlCharacter); err == nil {
+		event = bytes.Trim(event, ControlCharacterString)
+
+		payload := handler(event)
+		_, _ = conn.Write(append(payload, ControlCharacter))
 	}
 }
 
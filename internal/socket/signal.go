@@ -1,7 +1,88 @@
 package socket
 
-// SignalReload is the event for reloading all the Baito.
-var SignalReload = Signal("reload")
+import (
+	"bytes"
+	"encoding/json"
+)
 
-// A Signal is an event sent through a socket.
-type Signal []byte
+var (
+	// SignalReload is the event for reloading all the Baito.
+	SignalReload = Signal("reload")
+	// SignalStatus is the event for listing the state of all the Baito.
+	SignalStatus = Signal("status")
+	// SignalHistory is the event for listing the recorded runs of the Baito.
+	SignalHistory = Signal("history")
+	// SignalTrigger is the event for running immediately a Baito.
+	SignalTrigger = Signal("trigger")
+	// SignalPause is the event for pausing a shigoto or a Baito.
+	SignalPause = Signal("pause")
+	// SignalResume is the event for resuming a shigoto or a Baito.
+	SignalResume = Signal("resume")
+)
+
+type (
+	// A Signal is an event sent through a socket.
+	Signal []byte
+
+	// A Response is the answer to a signal carrying data.
+	Response struct {
+		Error string          `json:"error,omitempty"`
+		Data  json.RawMessage `json:"data,omitempty"`
+	}
+
+	// A Target designates a shigoto file or one of its Baito.
+	Target struct {
+		Shigoto string `json:"shigoto"`
+		Baito   string `json:"baito,omitempty"`
+	}
+
+	// A Trigger is the payload of SignalTrigger.
+	Trigger struct {
+		Target
+		Wait bool `json:"wait,omitempty"`
+	}
+)
+
+// With returns the event of the signal carrying the given payload.
+func (s Signal) With(payload any) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	event := append([]byte{}, s...)
+	event = append(event, ' ')
+	return append(event, data...), nil
+}
+
+// Is returns true if the given event is an occurrence of the signal.
+func (s Signal) Is(event []byte) bool {
+	name, _, _ := bytes.Cut(event, []byte{' '})
+	return bytes.Equal(name, s)
+}
+
+// Payload decodes the payload carried by the given event.
+func Payload(event []byte, v any) error {
+	_, data, ok := bytes.Cut(event, []byte{' '})
+	if !ok {
+		return nil
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// Reply encodes the given data or error as a Response.
+func Reply(data any, err error) []byte {
+	var response Response
+	if err != nil {
+		response.Error = err.Error()
+	} else {
+		response.Data, err = json.Marshal(data)
+		if err != nil {
+			response.Error = err.Error()
+		}
+	}
+
+	payload, _ := json.Marshal(response)
+	return payload
+}
@@ -0,0 +1,260 @@
+// Package history stores the runs and the state of the baito in an embedded database.
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Filename is the name of the database created in the history directory.
+const Filename = "shigoto.db"
+
+var bucketRuns = []byte("runs")
+
+// The causes of a run.
+const (
+	TriggerSchedule   = "schedule"
+	TriggerManual     = "manual"
+	TriggerCatchUp    = "catch_up"
+	TriggerDependency = "dependency"
+)
+
+type (
+	// A Record is the trace of a baito run.
+	Record struct {
+		ID            string        `json:"id"`
+		Shigoto       string        `json:"shigoto"`
+		Baito         string        `json:"baito"`
+		Trigger       string        `json:"trigger"`
+		ScheduledTime time.Time     `json:"scheduled_time"`
+		StartTime     time.Time     `json:"start_time"`
+		Duration      time.Duration `json:"duration"`
+		Attempts      int           `json:"attempts,omitempty"`
+		Error         string        `json:"error,omitempty"`
+		Commands      []Command     `json:"commands"`
+	}
+
+	// A Command is the trace of a command run by a baito.
+	Command struct {
+		Index     int           `json:"index"`
+		Label     string        `json:"label"`
+		Deferred  bool          `json:"deferred,omitempty"`
+		Ignored   bool          `json:"ignored,omitempty"`
+		Skipped   bool          `json:"skipped,omitempty"`
+		StartTime time.Time     `json:"start_time"`
+		Duration  time.Duration `json:"duration"`
+		ExitCode  int           `json:"exit_code,omitempty"`
+		Status    int           `json:"status,omitempty"`
+		Output    int64         `json:"output"`
+		Attempts  int           `json:"attempts,omitempty"`
+		Error     string        `json:"error,omitempty"`
+	}
+
+	// A Retention defines how many records are kept per baito.
+	// Zero values mean no limit.
+	Retention struct {
+		MaxRecords int
+		MaxAge     time.Duration
+	}
+
+	// A Query filters the records returned by the Store.
+	// Zero values mean no filtering.
+	Query struct {
+		Shigoto string    `json:"shigoto,omitempty"`
+		Baito   string    `json:"baito,omitempty"`
+		Since   time.Time `json:"since,omitzero"`
+		Until   time.Time `json:"until,omitzero"`
+		Failed  bool      `json:"failed,omitempty"`
+		Limit   int       `json:"limit,omitempty"`
+	}
+
+	// A Store persists the records.
+	Store struct {
+		db        *bolt.DB
+		retention Retention
+	}
+)
+
+// Succeeded returns true if the run has not failed.
+func (r Record) Succeeded() bool {
+	return r.Error == ""
+}
+
+// Open opens or creates the store in the given directory.
+func Open(directory string, retention Retention) (*Store, error) {
+	if err := os.MkdirAll(directory, 0o755); err != nil {
+		return nil, errors.Wrap(err, "history: could not create directory")
+	}
+
+	db, err := bolt.Open(filepath.Join(directory, Filename), 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, errors.Wrap(err, "history: could not open database")
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{bucketRuns, bucketPaused, bucketSuccesses} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "history: could not initialize database")
+	}
+
+	return &Store{
+		db:        db,
+		retention: retention,
+	}, nil
+}
+
+// Close closes the store.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save persists the given record and applies the retention of its baito.
+func (s *Store) Save(record Record) error {
+	payload, err := json.Marshal(record)
+	if err != nil {
+		return errors.Wrap(err, "history: could not encode record")
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		shigoto, err := tx.Bucket(bucketRuns).CreateBucketIfNotExists([]byte(record.Shigoto))
+		if err != nil {
+			return err
+		}
+
+		baito, err := shigoto.CreateBucketIfNotExists([]byte(record.Baito))
+		if err != nil {
+			return err
+		}
+
+		if err = baito.Put(key(record.StartTime, record.ID), payload); err != nil {
+			return err
+		}
+
+		return s.prune(baito)
+	})
+}
+
+// Find returns the records matching the given query, most recent first.
+func (s *Store) Find(q Query) ([]Record, error) {
+	var records []Record
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return each(tx.Bucket(bucketRuns), q.Shigoto, func(shigoto *bolt.Bucket) error {
+			return each(shigoto, q.Baito, func(baito *bolt.Bucket) error {
+				c := baito.Cursor()
+				for k, v := c.Last(); k != nil; k, v = c.Prev() {
+					var record Record
+					if err := json.Unmarshal(v, &record); err != nil {
+						return errors.Wrap(err, "history: could not decode record")
+					}
+
+					if !q.Until.IsZero() && record.StartTime.After(q.Until) {
+						continue
+					}
+					if !q.Since.IsZero() && record.StartTime.Before(q.Since) {
+						break
+					}
+					if q.Failed && record.Succeeded() {
+						continue
+					}
+
+					records = append(records, record)
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(records, func(a, b Record) int {
+		return b.StartTime.Compare(a.StartTime)
+	})
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[:q.Limit]
+	}
+	return records, nil
+}
+
+// Last returns the most recent record of the given baito.
+func (s *Store) Last(shigoto, baito string) (Record, bool, error) {
+	records, err := s.Find(Query{Shigoto: shigoto, Baito: baito, Limit: 1})
+	if err != nil || len(records) == 0 {
+		return Record{}, false, err
+	}
+	return records[0], true, nil
+}
+
+func (s *Store) prune(b *bolt.Bucket) error {
+	var keys [][]byte
+	c := b.Cursor()
+
+	count := 0
+	deadline := time.Time{}
+	if s.retention.MaxAge > 0 {
+		deadline = time.Now().Add(-s.retention.MaxAge)
+	}
+
+	for k, _ := c.Last(); k != nil; k, _ = c.Prev() {
+		count++
+
+		if s.retention.MaxRecords > 0 && count > s.retention.MaxRecords {
+			keys = append(keys, slices.Clone(k))
+			continue
+		}
+		if !deadline.IsZero() && timestamp(k).Before(deadline) {
+			keys = append(keys, slices.Clone(k))
+		}
+	}
+
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// each calls fn on the nested bucket named name or on all nested buckets if name is empty.
+func each(b *bolt.Bucket, name string, fn func(*bolt.Bucket) error) error {
+	if b == nil {
+		return nil
+	}
+
+	if name != "" {
+		if nested := b.Bucket([]byte(name)); nested != nil {
+			return fn(nested)
+		}
+		return nil
+	}
+
+	return b.ForEachBucket(func(k []byte) error {
+		return fn(b.Bucket(k))
+	})
+}
+
+// key returns a sortable key starting with the given time.
+func key(t time.Time, id string) []byte {
+	k := make([]byte, 8, 8+len(id))
+	binary.BigEndian.PutUint64(k, uint64(t.UnixNano()))
+	return append(k, id...)
+}
+
+func timestamp(k []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(k[:8])))
+}
@@ -0,0 +1,83 @@
+package history
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pkg/errors"
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketPaused = []byte("paused")
+
+// A Pause describes a paused shigoto or baito.
+// An empty Baito designates the whole shigoto.
+type Pause struct {
+	Shigoto string    `json:"shigoto"`
+	Baito   string    `json:"baito,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// Pause persists the given pause.
+func (s *Store) Pause(pause Pause) error {
+	k, err := json.Marshal([]string{pause.Shigoto, pause.Baito})
+	if err != nil {
+		return errors.Wrap(err, "history: could not encode pause")
+	}
+
+	v, err := json.Marshal(pause)
+	if err != nil {
+		return errors.Wrap(err, "history: could not encode pause")
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketPaused).Put(k, v)
+	})
+}
+
+// Resume removes the pause of the given baito.
+// An empty baito removes all the pauses of the given shigoto.
+func (s *Store) Resume(shigoto, baito string) error {
+	paused, err := s.Paused()
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketPaused)
+
+		for _, pause := range paused {
+			if pause.Shigoto != shigoto || (baito != "" && pause.Baito != baito) {
+				continue
+			}
+
+			k, err := json.Marshal([]string{pause.Shigoto, pause.Baito})
+			if err != nil {
+				return errors.Wrap(err, "history: could not encode pause")
+			}
+
+			if err = b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Paused returns all the persisted pauses.
+func (s *Store) Paused() ([]Pause, error) {
+	var paused []Pause
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketPaused).ForEach(func(_, v []byte) error {
+			var pause Pause
+			if err := json.Unmarshal(v, &pause); err != nil {
+				return errors.Wrap(err, "history: could not decode pause")
+			}
+
+			paused = append(paused, pause)
+			return nil
+		})
+	})
+	return paused, err
+}
@@ -0,0 +1,48 @@
+package history
+
+import (
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucketSuccesses = []byte("successes")
+
+// Succeeded persists the scheduled time of the last successful run of the given baito.
+func (s *Store) Succeeded(shigoto, baito string, scheduled time.Time) error {
+	v, err := scheduled.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(bucketSuccesses).CreateBucketIfNotExists([]byte(shigoto))
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(baito), v)
+	})
+}
+
+// LastSuccess returns the scheduled time of the last successful run of the given baito.
+func (s *Store) LastSuccess(shigoto, baito string) (time.Time, bool, error) {
+	var t time.Time
+	var ok bool
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketSuccesses).Bucket([]byte(shigoto))
+		if b == nil {
+			return nil
+		}
+
+		v := b.Get([]byte(baito))
+		if v == nil {
+			return nil
+		}
+
+		ok = true
+		return t.UnmarshalBinary(v)
+	})
+	return t, ok, err
+}
@@ -0,0 +1,28 @@
+package io
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// A Counter is a writer that counts the bytes written to the underlying writer.
+type Counter struct {
+	w io.Writer
+	n atomic.Int64
+}
+
+// NewCounter returns a new Counter writing to w.
+func NewCounter(w io.Writer) *Counter {
+	return &Counter{w: w}
+}
+
+func (c *Counter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Count returns the number of bytes written.
+func (c *Counter) Count() int64 {
+	return c.n.Load()
+}
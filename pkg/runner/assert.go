@@ -0,0 +1,227 @@
+package runner
+
+import (
+	"bytes"
+	"encoding/json"
+	nethttp "net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// An assertion defines the success of an HTTP response beyond its status code.
+	assertion struct {
+		bodyMatches *regexp.Regexp
+		jsonPaths   []jsonPath
+		headers     []string
+		maxLatency  time.Duration
+	}
+
+	// A jsonPath is a JSONPath expression and its expected value.
+	jsonPath struct {
+		path     string
+		segments []any // Keys (string) and indexes (int).
+		expected any
+	}
+)
+
+// parseAssertion parses the fields checking the response of a request:
+//
+//	body_matches: '"status":\s*"ok"'
+//	json_path:
+//	  $.status: ok
+//	  $.checks[0].healthy: true
+//	required_headers: [X-Request-Id]
+//	max_latency: 500ms
+func parseAssertion(payload map[string]any) (*assertion, error) {
+	assert := &assertion{}
+
+	if v, ok := payload["body_matches"]; ok {
+		pattern, ok := v.(string)
+		if !ok {
+			return nil, errors.New("taskfile: http: body_matches must be a string")
+		}
+
+		var err error
+		assert.bodyMatches, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, invalid("body_matches", errors.Wrap(err, "taskfile: http: body_matches"))
+		}
+	}
+
+	if v, ok := payload["json_path"]; ok {
+		paths, ok := v.(map[string]any)
+		if !ok {
+			return nil, errors.New("taskfile: http: json_path must be a map")
+		}
+
+		for path, expected := range paths {
+			segments, err := parseJSONPath(path)
+			if err != nil {
+				return nil, errors.Wrapf(err, "taskfile: http: json_path: %s", path)
+			}
+			assert.jsonPaths = append(assert.jsonPaths, jsonPath{path: path, segments: segments, expected: expected})
+		}
+
+		// The checks are done in a stable order so the reported failure is always the same.
+		slices.SortFunc(assert.jsonPaths, func(a, b jsonPath) int {
+			return strings.Compare(a.path, b.path)
+		})
+	}
+
+	if v, ok := payload["required_headers"]; ok {
+		headers, ok := v.([]any)
+		if !ok {
+			return nil, errors.New("taskfile: http: required_headers must be an array")
+		}
+
+		for _, header := range headers {
+			header, ok := header.(string)
+			if !ok {
+				return nil, errors.New("taskfile: http: required_headers must contain strings")
+			}
+			assert.headers = append(assert.headers, header)
+		}
+	}
+
+	if v, ok := payload["max_latency"]; ok {
+		duration, ok := v.(string)
+		if !ok {
+			return nil, errors.New("taskfile: http: max_latency must be a string")
+		}
+
+		var err error
+		assert.maxLatency, err = time.ParseDuration(duration)
+		if err != nil {
+			return nil, invalid("max_latency", errors.Wrap(err, "taskfile: http: max_latency"))
+		}
+	}
+
+	return assert, nil
+}
+
+// body returns true if the response body is needed by the assertion.
+func (a *assertion) body() bool {
+	return a.bodyMatches != nil || len(a.jsonPaths) > 0
+}
+
+// check returns the error of the first failed check of the response.
+// The latency is the duration until the response headers are received.
+func (a *assertion) check(resp *nethttp.Response, body []byte, latency time.Duration) error {
+	if a.maxLatency > 0 && latency > a.maxLatency {
+		return errors.Errorf("max_latency: response received in %s, more than %s", latency.Round(time.Millisecond), a.maxLatency)
+	}
+
+	for _, header := range a.headers {
+		if resp.Header.Get(header) == "" {
+			return errors.Errorf("required_headers: missing header %s", header)
+		}
+	}
+
+	if a.bodyMatches != nil && !a.bodyMatches.Match(body) {
+		return errors.Errorf("body_matches: body does not match '%s'", a.bodyMatches)
+	}
+
+	if len(a.jsonPaths) == 0 {
+		return nil
+	}
+
+	var document any
+	if err := json.Unmarshal(body, &document); err != nil {
+		return errors.Wrap(err, "json_path: decode JSON")
+	}
+
+	for _, path := range a.jsonPaths {
+		value, ok := path.lookup(document)
+		if !ok {
+			return errors.Errorf("json_path: %s not found", path.path)
+		}
+
+		// The values are compared in JSON so the numbers decoded from YAML and JSON are equal.
+		actual, _ := json.Marshal(value)
+		expected, _ := json.Marshal(path.expected)
+		if !bytes.Equal(actual, expected) {
+			return errors.Errorf("json_path: %s is %s, expected %s", path.path, actual, expected)
+		}
+	}
+	return nil
+}
+
+// parseJSONPath parses the supported subset of JSONPath: the root followed by
+// child keys (`.key` or `['key']`) and array indexes (`[0]`).
+func parseJSONPath(path string) ([]any, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, errors.New("must start with $")
+	}
+
+	var segments []any
+	for p := path[1:]; p != ""; {
+		switch p[0] {
+		case '.':
+			end := strings.IndexAny(p[1:], ".[") + 1
+			if end == 0 {
+				end = len(p)
+			}
+
+			key := p[1:end]
+			if key == "" {
+				return nil, errors.New("empty key")
+			}
+			segments = append(segments, key)
+			p = p[end:]
+		case '[':
+			end := strings.IndexByte(p, ']')
+			if end < 0 {
+				return nil, errors.New("unclosed bracket")
+			}
+
+			segment := p[1:end]
+			switch {
+			case len(segment) >= 2 && (segment[0] == '\'' || segment[0] == '"') && segment[len(segment)-1] == segment[0]:
+				segments = append(segments, segment[1:len(segment)-1])
+			default:
+				index, err := strconv.Atoi(segment)
+				if err != nil || index < 0 {
+					return nil, errors.Errorf("invalid index '%s'", segment)
+				}
+				segments = append(segments, index)
+			}
+			p = p[end+1:]
+		default:
+			return nil, errors.Errorf("unexpected character '%c'", p[0])
+		}
+	}
+	return segments, nil
+}
+
+// lookup returns the value of the path in the given JSON document.
+func (p jsonPath) lookup(document any) (any, bool) {
+	value := document
+	for _, segment := range p.segments {
+		switch segment := segment.(type) {
+		case string:
+			object, ok := value.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+
+			value, ok = object[segment]
+			if !ok {
+				return nil, false
+			}
+		case int:
+			array, ok := value.([]any)
+			if !ok || segment >= len(array) {
+				return nil, false
+			}
+
+			value = array[segment]
+		}
+	}
+	return value, true
+}
@@ -1,18 +1,19 @@
 package runner
 
 import (
-	"sync"
+	"context"
+	"fmt"
+	"os"
+	"time"
 
 	"github.com/mdouchement/logger"
 )
 
 type base struct {
-	sync.Mutex
 	ctx         Context
-	log         logger.Logger
+	label       string
 	ignoreError bool
 	deferrable  bool
-	err         error
 }
 
 func (r *base) IsErrorIgnored() bool {
@@ -23,16 +24,35 @@ func (r *base) IsDeferrable() bool {
 	return r.deferrable
 }
 
-func (r *base) Error() error {
-	r.Lock()
-	defer r.Unlock()
+// logger returns the logger of a run.
+func (r *base) logger(ctx context.Context) logger.Logger {
+	return logger.LogWith(ctx).WithPrefixf("[%s]", r.ctx.Name()).WithField("id", GenerateID())
+}
 
-	return r.err
+// result returns the result of a run starting now.
+func (r *base) result() Result {
+	return Result{
+		Label:     r.label,
+		Deferred:  r.deferrable,
+		Ignored:   r.ignoreError,
+		StartTime: time.Now(),
+	}
 }
 
-func (r *base) AttachLogger(l logger.Logger) {
-	r.Lock()
-	defer r.Unlock()
+// environ returns the environment of a script run: the daemon, baito and run environment variables.
+func (r *base) environ(ctx context.Context) []string {
+	environ := os.Environ()
+	for k, v := range r.ctx.Environment() {
+		environ = append(environ, fmt.Sprintf("%s=%s", k, v))
+	}
+	return append(environ, runEnviron(ctx)...)
+}
 
-	r.log = l
+// redirection opens the file where the outputs of a run are redirected.
+// It returns a nil file when the outputs are not redirected.
+func redirection(path string) (*os.File, error) {
+	if path == "" {
+		return nil, nil
+	}
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
 }
@@ -1,43 +1,82 @@
 package runner
 
-type chain struct {
-	base
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mdouchement/logger"
+)
 
+// ErrInterrupted is returned by an interrupted chain.
+var ErrInterrupted = errors.New("interrupted")
+
+type chain struct {
 	runners []Runner
 }
 
 // Chain wraps and runs sequentially the given runners.
+// The deferred runners are run at the end of the chain, even if it has been interrupted.
 func Chain(runners ...Runner) Runner {
 	return &chain{runners: runners}
 }
 
-func (r *chain) Run() {
-	r.Lock()
-	defer r.Unlock()
+func (r *chain) IsErrorIgnored() bool {
+	return false
+}
+
+func (r *chain) IsDeferrable() bool {
+	return false
+}
 
-	// Reset previous state
-	r.err = nil
+func (r *chain) Run(ctx context.Context) (result Result) {
+	result.StartTime = time.Now()
+	defer func() {
+		result.EndTime = time.Now()
+	}()
 
-	//
+	log := logger.LogWith(ctx)
+	ctx = withRegistry(ctx) // The outputs registered by the commands of the chain.
 
-	for _, runner := range r.runners {
-		runner.AttachLogger(r.log.WithField("chain", GenerateID()))
+	for i, runner := range r.runners {
+		ctx := logger.WithLogger(ctx, log.WithField("chain", GenerateID()))
 
 		if runner.IsDeferrable() {
-			defer func(runner Runner) {
-				runner.Run()
-				if !runner.IsErrorIgnored() && runner.Error() != nil {
-					r.err = runner.Error()
+			defer func() {
+				// The cleanup is not interrupted with the chain.
+				if r.run(context.WithoutCancel(ctx), i, runner, &result) {
+					result.Error = result.Results[len(result.Results)-1].Error
 				}
-			}(runner)
+			}()
 
 			continue
 		}
 
-		runner.Run()
-		if !runner.IsErrorIgnored() && runner.Error() != nil {
-			r.err = runner.Error()
-			return
+		if ctx.Err() != nil {
+			result.Error = ErrInterrupted
+			return result
+		}
+
+		if r.run(ctx, i, runner, &result) {
+			result.Error = result.Results[len(result.Results)-1].Error
+			if errors.Is(ctx.Err(), context.Canceled) {
+				result.Error = ErrInterrupted
+			}
+			return result
 		}
 	}
+
+	return result
+}
+
+// run runs the given runner, appends its result to the chain result and returns true if it has failed.
+func (r *chain) run(ctx context.Context, i int, runner Runner, result *Result) bool {
+	res := runner.Run(ctx)
+	res.Index = i
+	if !res.Skipped {
+		registryFrom(ctx).done(res)
+	}
+
+	result.Results = append(result.Results, res)
+	return res.Failed()
 }
@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"github.com/mdouchement/logger"
+	"github.com/mdouchement/shigoto/pkg/templater"
+	"github.com/pkg/errors"
+)
+
+// A conditional runner is run only when its condition is true.
+type conditional struct {
+	Runner
+	ctx   Context
+	label string
+	when  string
+}
+
+// newConditional returns a runner only run when the `when` field of the payload is true.
+// The condition is rendered at each run, before the command, with the values of the run.
+func newConditional(ctx Context, payload map[string]any) (Runner, error) {
+	when, ok := payload["when"].(string)
+	if !ok {
+		return nil, errors.New("when must be a string")
+	}
+	if err := templater.Parse(when); err != nil {
+		return nil, errors.Wrap(err, "when")
+	}
+
+	payload = maps.Clone(payload)
+	delete(payload, "when")
+
+	runner, err := newCommand(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conditional{
+		Runner: runner,
+		ctx:    ctx,
+		label:  label(payload),
+		when:   when,
+	}, nil
+}
+
+func (r *conditional) Run(ctx context.Context) Result {
+	log := logger.LogWith(ctx).WithPrefixf("[%s]", r.ctx.Name())
+
+	templater := templater.New(newRunContext(r.ctx, ctx))
+	value := strings.TrimSpace(templater.Replace(r.when))
+	if err := templater.Err(); err != nil {
+		err = errors.Wrap(err, "when")
+		log.WithField("ignored", r.IsErrorIgnored()).Error(err)
+
+		result := Result{
+			Label:     r.label,
+			Deferred:  r.IsDeferrable(),
+			Ignored:   r.IsErrorIgnored(),
+			StartTime: time.Now(),
+		}
+		return result.end(err)
+	}
+
+	if truthy(value) {
+		return r.Runner.Run(ctx)
+	}
+
+	log.Infof(`Skipping "%s" - when %q is %q`, r.label, r.when, value)
+	result := Result{
+		Label:     r.label,
+		Deferred:  r.IsDeferrable(),
+		Skipped:   true,
+		StartTime: time.Now(),
+	}
+	return result.end(nil)
+}
+
+// truthy returns false for the rendered conditions meaning false.
+func truthy(s string) bool {
+	switch strings.ToLower(s) {
+	case "", "false", "0", "no", "<no value>":
+		return false
+	}
+	return true
+}
+
+// label returns the label of a command from its payload.
+func label(payload map[string]any) string {
+	if label, ok := payload["label"].(string); ok {
+		return label
+	}
+
+	for k, v := range payload {
+		if _, ok := runners.runners[k]; !ok {
+			continue
+		}
+
+		if s, ok := v.(string); ok {
+			return strings.Split(strings.TrimSpace(s), "\n")[0]
+		}
+		return fmt.Sprintf("%s command", k)
+	}
+	return ""
+}
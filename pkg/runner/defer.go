@@ -1,9 +1,10 @@
 package runner
 
 import (
+	"context"
 	"fmt"
 
-	"github.com/mdouchement/shigoto/pkg/templater"
+	"github.com/mdouchement/logger"
 	"github.com/pkg/errors"
 )
 
@@ -13,18 +14,12 @@ type deferrable struct {
 	runner Runner
 }
 
-func (r *deferrable) Run() {
-	r.Lock()
-	defer r.Unlock()
+func (r *deferrable) Run(ctx context.Context) Result {
+	ctx = logger.WithLogger(ctx, logger.LogWith(ctx).WithPrefix("[defer]"))
 
-	// Reset previous state
-	r.err = nil
-
-	//
-
-	r.runner.AttachLogger(r.log.WithPrefix("[defer]"))
-	r.runner.Run()
-	r.err = r.runner.Error()
+	result := r.runner.Run(ctx)
+	result.Deferred = true
+	return result
 }
 
 func init() {
@@ -42,15 +37,12 @@ func init() {
 		}
 
 		var err error
-		templater := templater.New(ctx)
 
 		switch v := command.(type) {
 		case string:
-			v = templater.Replace(v)
-			deferrable.runner, err = lookup(ctx, map[string]any{"exec": v})
+			deferrable.runner, err = newCommand(ctx, map[string]any{"exec": v})
 		case map[string]any:
-			v = templater.ReplaceMapI(v)
-			deferrable.runner, err = lookup(ctx, v)
+			deferrable.runner, err = newCommand(ctx, v)
 		default:
 			return nil, errors.New("invalid command format")
 		}
@@ -1,88 +1,112 @@
 package runner
 
 import (
+	"context"
 	"fmt"
 	"os"
 	osexec "os/exec"
 	"time"
 
 	"github.com/gobs/args"
+	"github.com/mdouchement/shigoto/pkg/io"
 	"github.com/pkg/errors"
 )
 
+// waitDelay is the delay given to the children of a killed process to release its outputs.
+const waitDelay = 5 * time.Second
+
 type exec struct {
 	base
 
 	cmd      string
-	exec     *osexec.Cmd
-	redirect *os.File
+	redirect string
+	expect   *expectation
 }
 
-func (r *exec) Run() {
-	r.Lock()
-	defer r.Unlock()
-
-	// Reset previous state
-	r.err = nil
-
-	//
-
-	start := time.Now()
-	logger := r.log.WithPrefixf("[%s]", r.ctx.Name()).WithField("id", GenerateID())
+func (r *exec) Run(ctx context.Context) Result {
+	result := r.result()
+	logger := r.logger(ctx)
 	logger.Info(r.cmd)
-	if r.redirect != nil {
-		defer r.redirect.Sync()
-	}
 	if r.ctx.LogsFile() != nil {
 		defer r.ctx.LogsFile().Sync()
 	}
 
-	err := r.buildCommand()
+	redirect, err := redirection(r.redirect)
+	if err != nil {
+		err = errors.Wrap(err, "taskfile: exec: could not create logs redirection file")
+		logger.WithField("elapsed_time", time.Since(result.StartTime)).WithField("ignored", r.ignoreError).Error(err)
+		return result.end(err)
+	}
+	if redirect != nil {
+		defer redirect.Close()
+	}
+
+	cmd, err := r.buildCommand(ctx, redirect)
 	if err != nil {
-		r.err = err
-		logger.WithField("elapsed_time", time.Since(start)).WithField("ignored", r.ignoreError).Error(err)
-		return
+		logger.WithField("elapsed_time", time.Since(result.StartTime)).WithField("ignored", r.ignoreError).Error(err)
+		return result.end(err)
 	}
 
-	if err := r.exec.Run(); err != nil {
-		r.err = err
-		logger.WithField("elapsed_time", time.Since(start)).WithField("ignored", r.ignoreError).Error(err)
-		return
+	matcher := r.expect.matcher()
+	stdoutw, flushStdout := matcher.tee(captured(ctx, cmd.Stdout))
+	stderrw, flushStderr := matcher.tee(cmd.Stderr)
+
+	stdout, stderr := io.NewCounter(stdoutw), io.NewCounter(stderrw)
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	err = cmd.Run()
+	flushStdout()
+	flushStderr()
+	result.ExitCode = cmd.ProcessState.ExitCode()
+	result.Output = stdout.Count() + stderr.Count()
+	if err = r.expect.check(result.ExitCode, err, matcher); err != nil {
+		logger.WithField("elapsed_time", time.Since(result.StartTime)).WithField("exit_code", result.ExitCode).WithField("ignored", r.ignoreError).Error(err)
+		return result.end(err)
 	}
 
-	logger.WithField("elapsed_time", time.Since(start)).Info("finished")
+	logger.WithField("elapsed_time", time.Since(result.StartTime)).Info("finished")
+	return result.end(nil)
 }
 
-func (r *exec) buildCommand() error {
+func (r *exec) buildCommand(ctx context.Context, redirect *os.File) (*osexec.Cmd, error) {
 	args := args.GetArgs(r.cmd)
+	if len(args) == 0 {
+		return nil, errors.New("taskfile: exec: empty command")
+	}
+
 	bin, err := osexec.LookPath(args[0])
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	r.exec = &osexec.Cmd{
-		Path:   bin,
-		Args:   args,
-		Stdout: os.Stdout,
-		Stderr: os.Stderr,
-		Dir:    r.ctx.Workdir(),
+	// The process is killed when the context is done.
+	cmd := osexec.CommandContext(ctx, bin, args[1:]...)
+	cmd.Args = args
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Dir = r.ctx.Workdir()
+	cmd.WaitDelay = waitDelay
+
+	// The process inherits the environment of the daemon if the baito does not define one.
+	if len(r.ctx.Environment()) == 0 {
+		cmd.Env = os.Environ()
 	}
-
 	for k, v := range r.ctx.Environment() {
-		r.exec.Env = append(r.exec.Env, fmt.Sprintf("%s=%s", k, v))
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
+	cmd.Env = append(cmd.Env, runEnviron(ctx)...)
 
 	if r.ctx.LogsFile() != nil {
-		r.exec.Stdout = r.ctx.LogsFile()
-		r.exec.Stderr = r.ctx.LogsFile()
+		cmd.Stdout = r.ctx.LogsFile()
+		cmd.Stderr = r.ctx.LogsFile()
 	}
 
-	if r.redirect != nil {
-		r.exec.Stdout = r.redirect
-		r.exec.Stderr = r.redirect
+	if redirect != nil {
+		cmd.Stdout = redirect
+		cmd.Stderr = redirect
 	}
 
-	return nil
+	return cmd, nil
 }
 
 func init() {
@@ -106,8 +130,9 @@ func init() {
 		executor.cmd = executor.ctx.ExpandAll(executor.cmd)
 		executor.cmd, err = executor.ctx.ExpandTilde(executor.cmd)
 		if err != nil {
-			return nil, errors.Wrap(err, "taskfile: exec: could not expand command")
+			return nil, invalid("exec", errors.Wrap(err, "taskfile: exec: could not expand command"))
 		}
+		executor.label = executor.cmd
 
 		// Ignore error
 		if v, ok := payload["ignore_error"]; ok {
@@ -119,6 +144,12 @@ func init() {
 			executor.ignoreError = b
 		}
 
+		// Success of the process
+		executor.expect, err = parseExpectation("exec", payload)
+		if err != nil {
+			return nil, err
+		}
+
 		// Redirect command stdout/stderr to a file
 		if v, ok := payload["redirect"]; ok {
 			path, ok := v.(string)
@@ -128,15 +159,10 @@ func init() {
 			path = executor.ctx.ExpandAll(path)
 			path, err = executor.ctx.ExpandTilde(path)
 			if err != nil {
-				return nil, errors.Wrap(err, "taskfile: exec: could not expand logs redirection file path")
-			}
-
-			f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-			if err != nil {
-				return nil, errors.Wrap(err, "taskfile: exec: could not create logs redirection file")
+				return nil, invalid("redirect", errors.Wrap(err, "taskfile: exec: could not expand logs redirection file path"))
 			}
 
-			executor.redirect = f
+			executor.redirect = path
 		}
 
 		return executor, nil
@@ -0,0 +1,172 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// maxLineLength is the length after which a line of output is matched in several parts.
+const maxLineLength = 64 << 10
+
+type (
+	// An expectation defines the success of a process from its exit code and its output.
+	expectation struct {
+		exitCodes  []int
+		failIf     *regexp.Regexp
+		failUnless *regexp.Regexp
+	}
+
+	// A matcher matches the lines of the outputs of a process against the expectation.
+	matcher struct {
+		mu         sync.Mutex
+		expect     *expectation
+		failIf     bool
+		failUnless bool
+	}
+
+	// A lineWriter feeds a matcher with the lines of an output.
+	lineWriter struct {
+		matcher *matcher
+		line    []byte
+	}
+)
+
+// parseExpectation parses the fields defining the success of a process:
+//
+//	success_exit_codes: [0, 1]
+//	fail_if_output_matches: "(?i)error"
+//	fail_unless_output_matches: "done"
+func parseExpectation(name string, payload map[string]any) (*expectation, error) {
+	expect := &expectation{}
+
+	if v, ok := payload["success_exit_codes"]; ok {
+		codes, ok := v.([]any)
+		if !ok {
+			return nil, errors.Errorf("taskfile: %s: success_exit_codes must be an array", name)
+		}
+
+		for _, code := range codes {
+			code, ok := code.(int)
+			if !ok {
+				return nil, errors.Errorf("taskfile: %s: success_exit_codes must contain integers", name)
+			}
+			expect.exitCodes = append(expect.exitCodes, code)
+		}
+	}
+
+	for key, re := range map[string]**regexp.Regexp{"fail_if_output_matches": &expect.failIf, "fail_unless_output_matches": &expect.failUnless} {
+		v, ok := payload[key]
+		if !ok {
+			continue
+		}
+
+		pattern, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("taskfile: %s: %s must be a string", name, key)
+		}
+
+		var err error
+		*re, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, invalid(key, errors.Wrapf(err, "taskfile: %s: %s", name, key))
+		}
+	}
+
+	return expect, nil
+}
+
+// matcher returns a matcher of the outputs of a run or nil if the output is not checked.
+func (e *expectation) matcher() *matcher {
+	if e.failIf == nil && e.failUnless == nil {
+		return nil
+	}
+	return &matcher{expect: e}
+}
+
+// check returns the error of a process ended with the given exit code and error.
+// The exit error is dropped if the exit code is a success one.
+func (e *expectation) check(exitCode int, err error, m *matcher) error {
+	if len(e.exitCodes) > 0 {
+		switch {
+		case err == nil && !slices.Contains(e.exitCodes, 0):
+			return errors.New("exit status 0 is not a success exit code")
+		case err != nil && exitCode > 0 && slices.Contains(e.exitCodes, exitCode):
+			err = nil
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if m == nil {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.failIf {
+		return errors.Errorf("output matches '%s'", e.failIf)
+	}
+	if e.failUnless != nil && !m.failUnless {
+		return errors.Errorf("output does not match '%s'", e.failUnless)
+	}
+	return nil
+}
+
+// tee returns w also writing to the matcher, if any, and the function matching the last line.
+// Each output needs its own writer so their lines are not mixed.
+func (m *matcher) tee(w io.Writer) (io.Writer, func()) {
+	if m == nil {
+		return w, func() {}
+	}
+
+	lw := &lineWriter{matcher: m}
+	return io.MultiWriter(w, lw), lw.Flush
+}
+
+func (m *matcher) match(line []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.expect.failIf != nil && !m.failIf {
+		m.failIf = m.expect.failIf.Match(line)
+	}
+	if m.expect.failUnless != nil && !m.failUnless {
+		m.failUnless = m.expect.failUnless.Match(line)
+	}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			w.line = append(w.line, p...)
+			if len(w.line) >= maxLineLength {
+				w.Flush()
+			}
+			break
+		}
+
+		w.line = append(w.line, p[:i]...)
+		w.Flush()
+		p = p[i+1:]
+	}
+
+	return n, nil
+}
+
+// Flush matches the pending line.
+func (w *lineWriter) Flush() {
+	if len(w.line) > 0 {
+		w.matcher.match(w.line)
+	}
+	w.line = w.line[:0]
+}
@@ -0,0 +1,230 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mdouchement/logger"
+	"github.com/mdouchement/shigoto/pkg/templater"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A forEach runner runs its command once per item of a list.
+	forEach struct {
+		Runner
+		ctx         Context
+		label       string
+		items       any // A list, a template rendering a list or the name of a registered output.
+		concurrency int
+	}
+
+	// An iteration is the current item of a loop.
+	iteration struct {
+		index int
+		item  any
+	}
+
+	iterationKey struct{}
+)
+
+// newForEach returns a runner running the command of the payload for each item of its `for_each` field:
+//
+//	for_each: [a, b, c]              # A list.
+//	for_each: '{{.LIST | toJson}}'   # A template rendering a JSON array or a list of lines.
+//	for_each: FILES                  # The name of a registered output.
+//	concurrency: 2                   # The number of items run at once.
+func newForEach(ctx Context, payload map[string]any) (Runner, error) {
+	r := &forEach{
+		ctx:         ctx,
+		items:       payload["for_each"],
+		concurrency: 1,
+	}
+
+	switch v := r.items.(type) {
+	case []any:
+	case string:
+		if strings.Contains(v, "{{") {
+			if err := templater.Parse(v); err != nil {
+				return nil, errors.Wrap(err, "for_each")
+			}
+		} else if !registerName.MatchString(v) {
+			return nil, errors.Errorf("for_each: invalid registered output '%s'", v)
+		}
+	default:
+		return nil, errors.New("for_each must be an array or a string")
+	}
+
+	if v, ok := payload["concurrency"]; ok {
+		r.concurrency, ok = v.(int)
+		if !ok || r.concurrency < 1 {
+			return nil, errors.New("for_each: concurrency must be a positive integer")
+		}
+	}
+
+	payload = maps.Clone(payload)
+	delete(payload, "for_each")
+	delete(payload, "concurrency")
+
+	var err error
+	r.Runner, err = newCommand(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	r.label = label(payload)
+
+	return r, nil
+}
+
+func (r *forEach) Run(ctx context.Context) Result {
+	result := Result{
+		Label:     r.label,
+		Deferred:  r.IsDeferrable(),
+		Ignored:   r.IsErrorIgnored(),
+		StartTime: time.Now(),
+	}
+	log := logger.LogWith(ctx)
+
+	items, err := r.list(ctx)
+	if err != nil {
+		err = errors.Wrap(err, "for_each")
+		log.WithPrefixf("[%s]", r.ctx.Name()).WithField("ignored", r.IsErrorIgnored()).Error(err)
+		return result.end(err)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		failed  bool
+		slots   = make(chan struct{}, r.concurrency)
+		results = make([]Result, len(items))
+	)
+
+	n := 0
+	for i, item := range items {
+		slots <- struct{}{}
+
+		mu.Lock()
+		stop := failed && !r.IsErrorIgnored()
+		mu.Unlock()
+		if stop || ctx.Err() != nil {
+			break // The remaining items are not run after a failure or an interruption.
+		}
+
+		n++
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			ctx := context.WithValue(ctx, iterationKey{}, &iteration{index: i, item: item})
+			ctx = logger.WithLogger(ctx, log.WithField("item", i))
+
+			res := r.Runner.Run(ctx)
+			res.Index = i
+			results[i] = res
+
+			if res.Error != nil {
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	result.Results = results[:n]
+	err = join(result.Results)
+	if err == nil && n < len(items) {
+		err = ErrInterrupted
+	}
+	return result.end(err)
+}
+
+// list returns the items of the loop.
+func (r *forEach) list(ctx context.Context) ([]any, error) {
+	switch items := r.items.(type) {
+	case []any:
+		return items, nil
+	case string:
+		if !strings.Contains(items, "{{") {
+			value, ok := registryFrom(ctx).Values()[items]
+			if !ok {
+				return nil, errors.Errorf("no output registered as %s", items)
+			}
+			return split(value)
+		}
+
+		templater := templater.New(newRunContext(r.ctx, ctx))
+		value := templater.Replace(items)
+		if err := templater.Err(); err != nil {
+			return nil, err
+		}
+
+		var list []any
+		if err := json.Unmarshal([]byte(value), &list); err == nil {
+			return list, nil
+		}
+		return split(value)
+	}
+	return nil, nil
+}
+
+// split returns the items of a list or the non-empty lines of a string.
+func split(value any) ([]any, error) {
+	switch value := value.(type) {
+	case []any:
+		return value, nil
+	case string:
+		var items []any
+		for _, line := range strings.Split(value, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				items = append(items, line)
+			}
+		}
+		return items, nil
+	}
+	return nil, errors.Errorf("%T is not a list", value)
+}
+
+func iterationFrom(ctx context.Context) *iteration {
+	iteration, _ := ctx.Value(iterationKey{}).(*iteration)
+	return iteration
+}
+
+// Values returns the template variables of the item.
+func (i *iteration) Values() map[string]any {
+	if i == nil {
+		return nil
+	}
+
+	return map[string]any{
+		"Index": i.index,
+		"Item":  i.item,
+	}
+}
+
+// Environ returns the environment variables of the item, the item being encoded in JSON if it's not a string.
+func (i *iteration) Environ() []string {
+	if i == nil {
+		return nil
+	}
+
+	item, ok := i.item.(string)
+	if !ok {
+		b, _ := json.Marshal(i.item)
+		item = string(b)
+	}
+
+	return []string{
+		"SHIGOTO_INDEX=" + strconv.Itoa(i.index),
+		"SHIGOTO_ITEM=" + item,
+	}
+}
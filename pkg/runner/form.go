@@ -0,0 +1,182 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"maps"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	// A form is a body made of fields and files.
+	// It's sent URL encoded without files and as multipart otherwise.
+	form struct {
+		fields map[string]string
+		files  map[string][]string
+	}
+
+	// A requestBody is the body of a request, the files being streamed.
+	requestBody struct {
+		io.Reader
+		length      int64
+		contentType string
+		files       []*os.File
+	}
+)
+
+// parseForm parses the form fields of the payload, nil being returned if there are none:
+//
+//	form:
+//	  title: Daily report
+//	files:
+//	  report: ~/reports/daily.pdf
+//	  attachments: [/tmp/a.csv, /tmp/b.csv]
+func parseForm(ctx Context, payload map[string]any) (*form, error) {
+	_, fields := payload["form"]
+	_, files := payload["files"]
+	if !fields && !files {
+		return nil, nil
+	}
+
+	for _, key := range []string{"body", "body_file", "content_type"} {
+		if _, ok := payload[key]; ok {
+			return nil, errors.Errorf("taskfile: http: %s can't be used with form or files", key)
+		}
+	}
+
+	f := &form{
+		fields: map[string]string{},
+		files:  map[string][]string{},
+	}
+
+	if v, ok := payload["form"]; ok {
+		fields, ok := v.(map[string]any)
+		if !ok {
+			return nil, errors.New("taskfile: http: form must be a map")
+		}
+
+		for k, v := range fields {
+			value, ok := v.(string)
+			if !ok {
+				return nil, errors.Errorf("taskfile: http: form: %s must be a string", k)
+			}
+
+			f.fields[k] = ctx.ExpandAll(value)
+		}
+	}
+
+	if v, ok := payload["files"]; ok {
+		files, ok := v.(map[string]any)
+		if !ok {
+			return nil, errors.New("taskfile: http: files must be a map")
+		}
+
+		for k, v := range files {
+			var paths []any
+			switch v := v.(type) {
+			case string:
+				paths = []any{v}
+			case []any:
+				paths = v
+			default:
+				return nil, errors.Errorf("taskfile: http: files: %s must be a string or an array", k)
+			}
+
+			for _, path := range paths {
+				path, ok := path.(string)
+				if !ok {
+					return nil, errors.Errorf("taskfile: http: files: %s must contain strings", k)
+				}
+
+				path, err := ctx.ExpandTilde(ctx.ExpandAll(path))
+				if err != nil {
+					return nil, invalid("files", errors.Wrapf(err, "taskfile: http: files: could not expand %s path", k))
+				}
+				f.files[k] = append(f.files[k], path)
+			}
+		}
+	}
+
+	return f, nil
+}
+
+// body opens the body of the form.
+// The multipart body is the sequence of the encoded parts and the content of the files, so its length is known without reading the files.
+func (f *form) body() (*requestBody, error) {
+	if len(f.files) == 0 {
+		values := url.Values{}
+		for k, v := range f.fields {
+			values.Set(k, v)
+		}
+
+		data := values.Encode()
+		return &requestBody{
+			Reader:      strings.NewReader(data),
+			length:      int64(len(data)),
+			contentType: "application/x-www-form-urlencoded",
+		}, nil
+	}
+
+	var (
+		body    = &requestBody{}
+		buf     bytes.Buffer
+		readers []io.Reader
+		w       = multipart.NewWriter(&buf)
+	)
+
+	// flush appends the encoded parts written so far to the body.
+	flush := func() {
+		readers = append(readers, bytes.NewReader(bytes.Clone(buf.Bytes())))
+		body.length += int64(buf.Len())
+		buf.Reset()
+	}
+
+	// The writes to a bytes.Buffer never fail.
+	for _, name := range slices.Sorted(maps.Keys(f.fields)) {
+		w.WriteField(name, f.fields[name])
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(f.files)) {
+		for _, path := range f.files[name] {
+			file, err := os.Open(path)
+			if err != nil {
+				body.Close()
+				return nil, errors.Wrap(err, "files")
+			}
+			body.files = append(body.files, file)
+
+			info, err := file.Stat()
+			if err != nil {
+				body.Close()
+				return nil, errors.Wrap(err, "files")
+			}
+
+			w.CreateFormFile(name, filepath.Base(path))
+			flush()
+			readers = append(readers, file)
+			body.length += info.Size()
+		}
+	}
+
+	w.Close()
+	flush()
+
+	body.Reader = io.MultiReader(readers...)
+	body.contentType = w.FormDataContentType()
+	return body, nil
+}
+
+// Close closes the streamed files.
+func (b *requestBody) Close() error {
+	for _, file := range b.files {
+		file.Close()
+	}
+	return nil
+}
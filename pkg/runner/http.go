@@ -3,74 +3,275 @@ package runner
 import (
 	"bytes"
 	"context"
-	"io/ioutil"
+	"fmt"
+	"io"
 	nethttp "net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/mdouchement/logger"
 	"github.com/pkg/errors"
-	"github.com/slok/goresilience"
-	"github.com/slok/goresilience/retry"
 )
 
-type http struct {
-	base
+type (
+	http struct {
+		base
 
-	url         *url.URL
-	method      string
-	contentType string
-	body        string
-	retry       goresilience.Runner
+		url            *url.URL
+		location       string // The URL logged instead of url, its environment variables are not expanded.
+		method         string
+		header         nethttp.Header
+		contentType    string
+		username       string
+		password       string
+		bearerToken    string
+		body           string
+		bodyFile       string
+		form           *form
+		output         string
+		assert         *assertion
+		transport      transport
+		expectedStatus []statusRange
+		retryOnStatus  []statusRange
+		retry          *RetryPolicy
+	}
+
+	// A statusRange is an inclusive range of HTTP status codes.
+	statusRange struct {
+		min int
+		max int
+	}
+)
+
+func (r *http) Run(ctx context.Context) Result {
+	result := r.result()
+	logger := r.logger(ctx)
+	logger.Info(r.label)
+	if r.ctx.LogsFile() != nil {
+		defer r.ctx.LogsFile().Sync()
+	}
+
+	err := r.do(ctx, logger, &result)
+	if err != nil {
+		logger.WithField("elapsed_time", time.Since(result.StartTime)).WithField("ignored", r.ignoreError).Error(err)
+		return result.end(err)
+	}
+
+	logger.WithField("elapsed_time", time.Since(result.StartTime)).WithField("code", result.Status).Info("finished")
+	return result.end(nil)
 }
 
-func (r *http) Run() {
-	r.Lock()
-	defer r.Unlock()
+// do sends the request and fills the result with the response.
+func (r *http) do(ctx context.Context, logger logger.Logger, result *Result) error {
+	data, err := r.requestBody()
+	if err != nil {
+		return err
+	}
+	defer data.Close()
 
-	// Reset previous state
-	r.err = nil
+	var reader io.Reader
+	if data.length > 0 {
+		reader = data // An empty body is not sent.
+	}
 
-	//
+	// The request is aborted when the context is done.
+	request, err := nethttp.NewRequestWithContext(ctx, strings.ToUpper(r.method), r.url.String(), reader)
+	if err != nil {
+		return err
+	}
+	request.ContentLength = data.length
+	request.Header = r.header.Clone()
+	if data.contentType != "" {
+		request.Header.Set("Content-Type", data.contentType)
+	}
+	if r.username != "" || r.password != "" {
+		request.SetBasicAuth(r.username, r.password)
+	}
+	if r.bearerToken != "" {
+		request.Header.Set("Authorization", "Bearer "+r.bearerToken)
+	}
 
-	start := time.Now()
-	logger := r.log.WithPrefixf("[%s]", r.ctx.Name()).WithField("id", GenerateID())
-	logger.Infof("%s %s", strings.ToUpper(r.method), r.url)
-	if r.ctx.LogsFile() != nil {
-		defer r.ctx.LogsFile().Sync()
+	client, err := r.transport.client()
+	if err != nil {
+		return err
 	}
 
-	err := r.retry.Run(context.Background(), func(ctx context.Context) error {
-		request, err := nethttp.NewRequest(strings.ToUpper(r.method), r.url.String(), bytes.NewBufferString(r.body))
-		if err != nil {
-			return err
+	start := time.Now()
+	resp, err := client.Do(request)
+	if err != nil {
+		// The error of the client contains the expanded URL, so it's replaced to not log its secrets (e.g. a token in the query).
+		var uerr *url.Error
+		if errors.As(err, &uerr) {
+			err = &url.Error{Op: uerr.Op, URL: r.location, Err: uerr.Err}
 		}
-		request.Close = true
-		request.Header.Set("Content-Type", r.contentType)
+		return err
+	}
+	defer resp.Body.Close()
+	latency := time.Since(start)
+	result.Status = resp.StatusCode
+	output := captured(ctx, io.Discard) // The body is registered whatever the status.
+	expected := matchStatus(r.expectedStatus, resp.StatusCode)
+
+	var body bytes.Buffer
+	if !expected || r.assert.body() {
+		output = io.MultiWriter(output, &body)
+	}
 
-		resp, err := nethttp.DefaultClient.Do(request)
+	var file *os.File
+	if expected && r.output != "" {
+		// The body is written to a temporary file renamed on success so a failed request does not replace a previous output.
+		file, err = os.CreateTemp(filepath.Dir(r.output), "."+filepath.Base(r.output)+".*")
 		if err != nil {
-			return err
+			return errors.Wrap(err, "output")
 		}
-		defer resp.Body.Close()
+		defer os.Remove(file.Name())
+		defer file.Close()
+
+		output = io.MultiWriter(output, file)
+	}
+
+	result.Output, err = io.Copy(output, resp.Body)
+	if err != nil {
+		return err
+	}
 
-		switch {
-		case resp.StatusCode < 400:
-			return nil
-		case resp.StatusCode >= 400:
-			body, err := ioutil.ReadAll(resp.Body)
-			logger.WithField("code", resp.StatusCode).WithField("status", resp.Status).Error(string(body))
-			return err
+	if expected {
+		if err = r.assert.check(resp, body.Bytes(), latency); err != nil {
+			return errors.Wrap(err, "assert")
+		}
+		if file != nil {
+			return r.save(file)
 		}
 		return nil
-	})
+	}
+
+	logger.WithField("code", resp.StatusCode).WithField("status", resp.Status).Error(body.String())
+
+	err = errors.Errorf("unexpected status %s", resp.Status)
+	if !matchStatus(r.retryOnStatus, resp.StatusCode) {
+		return permanent(err)
+	}
+
+	result.RetryAfter = retryAfter(resp.Header.Get("Retry-After"))
+	return err
+}
+
+// requestBody opens the body of the request, the files being streamed.
+func (r *http) requestBody() (*requestBody, error) {
+	if r.form != nil {
+		return r.form.body()
+	}
+
+	if r.bodyFile == "" {
+		return &requestBody{
+			Reader:      strings.NewReader(r.body),
+			length:      int64(len(r.body)),
+			contentType: r.contentType,
+		}, nil
+	}
+
+	file, err := os.Open(r.bodyFile)
 	if err != nil {
-		r.err = err
-		logger.WithField("elapsed_time", time.Since(start)).WithField("ignored", r.ignoreError).Error(err)
-		return
+		return nil, errors.Wrap(err, "body_file")
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "body_file")
+	}
+
+	return &requestBody{
+		Reader:      file,
+		length:      info.Size(),
+		contentType: r.contentType,
+		files:       []*os.File{file},
+	}, nil
+}
+
+// save moves the written temporary file to the output path.
+func (r *http) save(file *os.File) error {
+	if err := file.Close(); err != nil {
+		return errors.Wrap(err, "output")
+	}
+
+	// CreateTemp creates the file with the 0600 permissions.
+	if err := os.Chmod(file.Name(), 0o644); err != nil {
+		return errors.Wrap(err, "output")
+	}
+	return errors.Wrap(os.Rename(file.Name(), r.output), "output")
+}
+
+// retryAfter returns the delay of a Retry-After header, either a number of seconds or a date.
+func retryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+
+	if t, err := nethttp.ParseTime(header); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// parseStatus parses a list of status codes (`200`) and ranges (`200-299` or `2xx`).
+func parseStatus(key string, v any) ([]statusRange, error) {
+	values, ok := v.([]any)
+	if !ok {
+		return nil, errors.Errorf("taskfile: http: %s must be an array", key)
+	}
+
+	var ranges []statusRange
+	for _, v := range values {
+		switch v := v.(type) {
+		case int:
+			ranges = append(ranges, statusRange{min: v, max: v})
+		case string:
+			var r statusRange
+			var err error
+
+			switch min, max, ok := strings.Cut(v, "-"); {
+			case ok:
+				r.min, err = strconv.Atoi(strings.TrimSpace(min))
+				if err == nil {
+					r.max, err = strconv.Atoi(strings.TrimSpace(max))
+				}
+			case len(v) == 3 && strings.HasSuffix(strings.ToLower(v), "xx"):
+				r.min, err = strconv.Atoi(v[:1])
+				r.min *= 100
+				r.max = r.min + 99
+			default:
+				r.min, err = strconv.Atoi(v)
+				r.max = r.min
+			}
+
+			if err != nil || r.min > r.max {
+				return nil, invalid(key, errors.Errorf("taskfile: http: %s: invalid status '%s'", key, v))
+			}
+			ranges = append(ranges, r)
+		default:
+			return nil, errors.Errorf("taskfile: http: %s must contain integers or strings", key)
+		}
 	}
+	return ranges, nil
+}
 
-	logger.WithField("elapsed_time", time.Since(start)).Info("finished")
+// matchStatus returns true if the status code is in one of the ranges.
+func matchStatus(ranges []statusRange, code int) bool {
+	for _, r := range ranges {
+		if r.min <= code && code <= r.max {
+			return true
+		}
+	}
+	return false
 }
 
 func init() {
@@ -98,12 +299,15 @@ func init() {
 			base: base{
 				ctx: ctx,
 			},
-			method: nethttp.MethodGet,
+			method:         nethttp.MethodGet,
+			header:         nethttp.Header{},
+			expectedStatus: []statusRange{{min: 100, max: 399}},
+			retryOnStatus:  []statusRange{{min: 500, max: 599}, {min: 429, max: 429}},
 		}
 
-		requester.url, err = url.Parse(rawurl)
+		requester.url, err = url.Parse(ctx.ExpandAll(rawurl))
 		if err != nil {
-			return nil, errors.Wrap(err, "could not parse URL")
+			return nil, invalid("http", errors.Wrap(err, "taskfile: http: could not parse URL"))
 		}
 
 		//
@@ -115,7 +319,7 @@ func init() {
 			}
 
 			if !supported[strings.ToUpper(method)] {
-				return nil, errors.Errorf("taskfile: http: unsupported method '%s'", method)
+				return nil, invalid("method", errors.Errorf("taskfile: http: unsupported method '%s'", method))
 			}
 
 			requester.method = method
@@ -127,7 +331,56 @@ func init() {
 				return nil, errors.New("taskfile: http: content_type must be a string")
 			}
 
-			requester.contentType = ct
+			requester.contentType = ctx.ExpandAll(ct)
+		}
+
+		if v, ok := payload["headers"]; ok {
+			headers, ok := v.(map[string]any)
+			if !ok {
+				return nil, errors.New("taskfile: http: headers must be a map")
+			}
+
+			for k, v := range headers {
+				value, ok := v.(string)
+				if !ok {
+					return nil, errors.Errorf("taskfile: http: headers: %s must be a string", k)
+				}
+
+				requester.header.Add(k, ctx.ExpandAll(value))
+			}
+		}
+
+		if v, ok := payload["basic_auth"]; ok {
+			auth, ok := v.(map[string]any)
+			if !ok {
+				return nil, errors.New("taskfile: http: basic_auth must be a map")
+			}
+
+			for key, field := range map[string]*string{"username": &requester.username, "password": &requester.password} {
+				v, ok := auth[key]
+				if !ok {
+					continue
+				}
+
+				s, ok := v.(string)
+				if !ok {
+					return nil, errors.Errorf("taskfile: http: basic_auth: %s must be a string", key)
+				}
+				*field = ctx.ExpandAll(s)
+			}
+		}
+
+		if v, ok := payload["bearer_token"]; ok {
+			if _, ok := payload["basic_auth"]; ok {
+				return nil, errors.New("taskfile: http: basic_auth and bearer_token are mutually exclusive")
+			}
+
+			token, ok := v.(string)
+			if !ok {
+				return nil, errors.New("taskfile: http: bearer_token must be a string")
+			}
+
+			requester.bearerToken = ctx.ExpandAll(token)
 		}
 
 		if v, ok := payload["body"]; ok {
@@ -136,31 +389,108 @@ func init() {
 				return nil, errors.New("taskfile: http: body must be a string")
 			}
 
-			requester.body = body
+			requester.body = ctx.ExpandAll(body)
 		}
 
-		//
-		// Retry
-		config := retry.Config{}
-		if v, ok := payload["retry"]; ok {
-			times, ok := v.(int)
+		if v, ok := payload["body_file"]; ok {
+			if _, ok := payload["body"]; ok {
+				return nil, errors.New("taskfile: http: body and body_file are mutually exclusive")
+			}
+
+			path, ok := v.(string)
 			if !ok {
-				return nil, errors.New("taskfile: http: retry must be an integer")
+				return nil, errors.New("taskfile: http: body_file must be a string")
+			}
+			path, err = ctx.ExpandTilde(ctx.ExpandAll(path))
+			if err != nil {
+				return nil, invalid("body_file", errors.Wrap(err, "taskfile: http: could not expand body_file path"))
+			}
+
+			requester.bodyFile = path
+		}
+
+		requester.form, err = parseForm(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		// The environment variables are not expanded in the label so they are not logged.
+		requester.location = ctx.ExpandVariables(rawurl)
+		if u, err := url.Parse(requester.location); err == nil && u.User != nil {
+			requester.location = u.Redacted()
+		}
+		requester.label = fmt.Sprintf("%s %s", strings.ToUpper(requester.method), requester.location)
+
+		//
+		// Response
+		if v, ok := payload["expected_status"]; ok {
+			requester.expectedStatus, err = parseStatus("expected_status", v)
+			if err != nil {
+				return nil, err
 			}
-			config.Times = int(times)
 		}
-		if v, ok := payload["retry_interval"]; ok {
-			duration, ok := v.(string)
-			if !ok {
-				return nil, errors.New("taskfile: http: retry_interval must be a string")
+
+		if v, ok := payload["retry_on_status"]; ok {
+			requester.retryOnStatus, err = parseStatus("retry_on_status", v)
+			if err != nil {
+				return nil, err
 			}
+		}
 
-			config.WaitBase, err = time.ParseDuration(duration)
+		requester.assert, err = parseAssertion(payload)
+		if err != nil {
+			return nil, err
+		}
+
+		if v, ok := payload["output"]; ok {
+			path, ok := v.(string)
+			if !ok {
+				return nil, errors.New("taskfile: http: output must be a string")
+			}
+			path, err = ctx.ExpandTilde(ctx.ExpandAll(path))
 			if err != nil {
-				return nil, errors.Wrap(err, "taskfile: http: retry_interval")
+				return nil, invalid("output", errors.Wrap(err, "taskfile: http: could not expand output path"))
+			}
+
+			requester.output = path
+		}
+
+		//
+		// Transport
+		requester.transport, err = parseTransport(ctx, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		//
+		// Retry
+		// The legacy retry fields define the default policy, overridden by the common retry block.
+		if _, ok := payload["retry"].(map[string]any); !ok {
+			requester.retry = &RetryPolicy{
+				attempts: 4,
+				interval: 20 * time.Millisecond,
+				backoff:  BackoffJitter,
+			}
+
+			if v, ok := payload["retry"]; ok {
+				times, ok := v.(int)
+				if !ok || times < 0 {
+					return nil, errors.New("taskfile: http: retry must be an integer or a map")
+				}
+				requester.retry.attempts = times + 1
+			}
+			if v, ok := payload["retry_interval"]; ok {
+				duration, ok := v.(string)
+				if !ok {
+					return nil, errors.New("taskfile: http: retry_interval must be a string")
+				}
+
+				requester.retry.interval, err = time.ParseDuration(duration)
+				if err != nil {
+					return nil, invalid("retry_interval", errors.Wrap(err, "taskfile: http: retry_interval"))
+				}
 			}
 		}
-		requester.retry = retry.New(config)
 
 		//
 		// IgnoreError
@@ -176,3 +506,7 @@ func init() {
 		return requester, nil
 	})
 }
+
+func (r *http) retryPolicy() *RetryPolicy {
+	return r.retry
+}
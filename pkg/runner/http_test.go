@@ -0,0 +1,36 @@
+package runner
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mdouchement/logger"
+)
+
+// A secretContext expands the SECRET environment variable.
+type secretContext struct {
+	testContext
+}
+
+func (secretContext) ExpandAll(s string) string { return strings.ReplaceAll(s, "${SECRET}", "s3cr3t") }
+func (secretContext) ExpandEnv(s string) string { return strings.ReplaceAll(s, "${SECRET}", "s3cr3t") }
+
+func TestHTTPErrorRedacted(t *testing.T) {
+	server := httptest.NewServer(nil)
+	server.Close() // The requests are refused.
+
+	runner, err := Lookup(secretContext{}, map[string]any{"http": server.URL + "/hook?token=${SECRET}", "retry": 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := runner.Run(logger.WithLogger(context.Background(), logger.NewNullLogger()))
+	if result.Error == nil {
+		t.Fatal("expected an error")
+	}
+	if message := result.Error.Error(); strings.Contains(message, "s3cr3t") || !strings.Contains(message, "token=${SECRET}") {
+		t.Errorf("error: got %q, expected the unexpanded URL", message)
+	}
+}
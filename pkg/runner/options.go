@@ -0,0 +1,121 @@
+package runner
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type (
+	timeout struct {
+		Runner
+		duration time.Duration
+	}
+
+	labeled struct {
+		Runner
+		label string
+	}
+)
+
+// options wraps the given runner according to the fields supported by all the runners:
+//   - timeout: the maximum duration of an attempt of the command
+//   - label: the label of the command in the results
+//   - register: the variable storing the output of the command (exec, sh, yaegi, tengo and http)
+//   - retry: the policy applied when the command fails
+func options(ctx Context, name string, runner Runner, payload map[string]any) (Runner, error) {
+	var policy *RetryPolicy
+	if retrier, ok := runner.(retrier); ok {
+		policy = retrier.retryPolicy()
+	}
+
+	if v, ok := payload["timeout"]; ok {
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("taskfile: %s: timeout must be a string", name)
+		}
+
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, invalid("timeout", errors.Wrapf(err, "taskfile: %s: timeout", name))
+		}
+
+		runner = Timeout(runner, d)
+	}
+
+	if v, ok := payload["label"]; ok {
+		label, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("taskfile: %s: label must be a string", name)
+		}
+
+		runner = &labeled{
+			Runner: runner,
+			label:  label,
+		}
+	}
+
+	if v, ok := payload["register"]; ok {
+		switch name {
+		case "exec", "sh", "yaegi", "tengo", "http":
+		default:
+			return nil, errors.Errorf("taskfile: %s: register is not supported", name)
+		}
+
+		var err error
+		runner, err = parseRegister(ctx, runner, v)
+		if err != nil {
+			return nil, nested("register", errors.Wrapf(err, "taskfile: %s: register", name))
+		}
+	}
+
+	if v, ok := payload["retry"]; ok && policy == nil {
+		var err error
+		policy, err = ParseRetry(v)
+		if err != nil {
+			return nil, nested("retry", errors.Wrapf(err, "taskfile: %s: retry", name))
+		}
+	}
+	if policy != nil {
+		runner = &retry{
+			Runner: runner,
+			ctx:    ctx,
+			policy: policy,
+		}
+	}
+
+	return runner, nil
+}
+
+// Timeout cancels the given runner when it runs longer than the given duration.
+// A zero duration means no timeout.
+func Timeout(runner Runner, d time.Duration) Runner {
+	if d <= 0 {
+		return runner
+	}
+
+	return &timeout{
+		Runner:   runner,
+		duration: d,
+	}
+}
+
+func (r *timeout) Run(ctx context.Context) Result {
+	parent := ctx
+
+	ctx, cancel := context.WithTimeout(ctx, r.duration)
+	defer cancel()
+
+	result := r.Runner.Run(ctx)
+	if result.Error != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) && parent.Err() == nil {
+		result.Error = errors.Wrapf(result.Error, "timed out after %s", r.duration)
+	}
+	return result
+}
+
+func (r *labeled) Run(ctx context.Context) Result {
+	result := r.Runner.Run(ctx)
+	result.Label = r.label
+	return result
+}
@@ -0,0 +1,178 @@
+package runner
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/mdouchement/logger"
+	"github.com/pkg/errors"
+)
+
+type parallel struct {
+	base
+
+	runners       []Runner
+	maxConcurrent int
+	failFast      bool
+}
+
+func (r *parallel) Run(ctx context.Context) (result Result) {
+	result = r.result()
+	log := logger.LogWith(ctx)
+	r.logger(ctx).Infof("Running %d commands in parallel", len(r.runners))
+
+	// The deferred commands of the group are run in reverse order at the end of the group, even if it has been interrupted.
+	var deferred []int
+	defer func() {
+		for _, i := range slices.Backward(deferred) {
+			ctx := logger.WithLogger(context.WithoutCancel(ctx), log.WithPrefixf("[parallel#%d]", i))
+
+			res := r.runners[i].Run(ctx)
+			res.Index = i
+			result.Results = append(result.Results, res)
+		}
+
+		if err := r.error(result.Results); err != nil {
+			result.Error = err
+		}
+		result.EndTime = time.Now()
+	}()
+
+	group, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		slots = make(chan struct{}, r.maxConcurrent)
+	)
+
+	for i, runner := range r.runners {
+		if runner.IsDeferrable() {
+			deferred = append(deferred, i)
+			continue
+		}
+
+		slots <- struct{}{}
+		if group.Err() != nil {
+			break // Interrupted or failed fast.
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			res := runner.Run(logger.WithLogger(group, log.WithPrefixf("[parallel#%d]", i)))
+			res.Index = i
+			if res.Error != nil && group.Err() != nil && ctx.Err() == nil {
+				res.Error = ErrInterrupted // Cancelled by the failure of another command.
+			}
+
+			mu.Lock()
+			result.Results = append(result.Results, res)
+			mu.Unlock()
+
+			if res.Failed() && r.failFast {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	slices.SortFunc(result.Results, func(a, b Result) int {
+		return cmp.Compare(a.Index, b.Index)
+	})
+
+	if result.Error = r.error(result.Results); result.Error != nil {
+		r.logger(ctx).WithField("elapsed_time", time.Since(result.StartTime)).WithField("ignored", r.ignoreError).Error(result.Error)
+		return result
+	}
+
+	if ctx.Err() != nil {
+		result.Error = ErrInterrupted
+		return result
+	}
+
+	r.logger(ctx).WithField("elapsed_time", time.Since(result.StartTime)).Info("finished")
+	return result
+}
+
+// error returns the combined errors of the failed commands.
+func (r *parallel) error(results []Result) error {
+	var failures []Result
+	for _, result := range results {
+		if result.Failed() {
+			failures = append(failures, result)
+		}
+	}
+	return join(failures)
+}
+
+func init() {
+	Register("parallel", func(ctx Context, payload map[string]any) (Runner, error) {
+		commands, ok := payload["parallel"].([]any)
+		if !ok {
+			return nil, errors.New("taskfile: parallel: commands must be an array")
+		}
+
+		executor := &parallel{
+			base: base{
+				ctx:   ctx,
+				label: fmt.Sprintf("%d parallel commands", len(commands)),
+			},
+			maxConcurrent: len(commands),
+		}
+
+		for i, command := range commands {
+			var err error
+			var runner Runner
+
+			switch v := command.(type) {
+			case string:
+				runner, err = newCommand(ctx, map[string]any{"exec": v})
+			case map[string]any:
+				runner, err = newCommand(ctx, v)
+			default:
+				return nil, errors.Errorf("taskfile: parallel: [%d]: invalid command format", i)
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "taskfile: parallel: [%d]", i)
+			}
+
+			executor.runners = append(executor.runners, runner)
+		}
+
+		if v, ok := payload["max_concurrent"]; ok {
+			executor.maxConcurrent, ok = v.(int)
+			if !ok || executor.maxConcurrent < 1 {
+				return nil, errors.New("taskfile: parallel: max_concurrent must be a positive integer")
+			}
+		}
+
+		if v, ok := payload["fail_fast"]; ok {
+			executor.failFast, ok = v.(bool)
+			if !ok {
+				return nil, errors.New("taskfile: parallel: fail_fast field must be a boolean")
+			}
+		}
+
+		// Ignore error
+		if v, ok := payload["ignore_error"]; ok {
+			b, ok := v.(bool)
+			if !ok {
+				return nil, errors.New("taskfile: parallel: ignore_error field must be a boolean")
+			}
+
+			executor.ignoreError = b
+		}
+
+		return executor, nil
+	})
+}
@@ -0,0 +1,246 @@
+package runner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mdouchement/logger"
+	"github.com/pkg/errors"
+)
+
+// DefaultRegisterLimit is the default maximum size of a registered output.
+const DefaultRegisterLimit = 64 << 10
+
+var (
+	registerName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+	// The environment variables a registered output can't override, even when the daemon does not define them.
+	reservedNames = []string{"PATH", "HOME", "SHELL", "IFS", "USER", "PWD", "TMPDIR", "LD_PRELOAD", "LD_LIBRARY_PATH"}
+)
+
+type (
+	// A registered runner stores its output in a variable of the run.
+	registered struct {
+		Runner
+		ctx   Context
+		name  string
+		limit int64
+		json  bool
+	}
+
+	// A registry holds the state shared by the commands of a run:
+	// the registered outputs and the result of the previous command.
+	registry struct {
+		mu       sync.Mutex
+		raw      map[string]string
+		values   map[string]any
+		previous Result
+	}
+
+	// A capture is a bounded buffer of the output of a command.
+	capture struct {
+		mu       sync.Mutex
+		buf      bytes.Buffer
+		limit    int64
+		exceeded bool
+	}
+
+	registryKey struct{}
+	captureKey  struct{}
+)
+
+// parseRegister parses the register field:
+//
+//	register: NAME
+//
+// or
+//
+//	register:
+//	  name: NAME
+//	  limit: 65536
+//	  json: true
+func parseRegister(ctx Context, runner Runner, v any) (Runner, error) {
+	r := &registered{
+		Runner: runner,
+		ctx:    ctx,
+		limit:  DefaultRegisterLimit,
+	}
+
+	switch v := v.(type) {
+	case string:
+		r.name = v
+	case map[string]any:
+		r.name, _ = v["name"].(string)
+
+		if v, ok := v["limit"]; ok {
+			limit, ok := v.(int)
+			if !ok || limit < 1 {
+				return nil, errors.New("limit must be a positive integer")
+			}
+			r.limit = int64(limit)
+		}
+
+		if v, ok := v["json"]; ok {
+			r.json, ok = v.(bool)
+			if !ok {
+				return nil, errors.New("json must be a boolean")
+			}
+		}
+	default:
+		return nil, errors.New("must be a string or a map")
+	}
+
+	if !registerName.MatchString(r.name) {
+		return nil, invalid("name", errors.Errorf("invalid name '%s'", r.name))
+	}
+	if reserved(ctx, r.name) {
+		return nil, invalid("name", errors.Errorf("name '%s' is already an environment variable", r.name))
+	}
+
+	return r, nil
+}
+
+// reserved returns true when the given name is an environment variable of the commands,
+// a registered output being also exposed as an environment variable.
+func reserved(ctx Context, name string) bool {
+	if _, ok := ctx.Environment()[name]; ok {
+		return true
+	}
+	if _, ok := os.LookupEnv(name); ok {
+		return true
+	}
+	return slices.Contains(reservedNames, name) || strings.HasPrefix(name, "SHIGOTO_")
+}
+
+func (r *registered) Run(ctx context.Context) Result {
+	capture := &capture{limit: r.limit}
+	result := r.Runner.Run(context.WithValue(ctx, captureKey{}, capture))
+	if result.Error != nil {
+		return result
+	}
+
+	value, err := r.value(capture)
+	if err != nil {
+		result.Error = errors.Wrapf(err, "register: %s", r.name)
+		logger.LogWith(ctx).WithPrefixf("[%s]", r.ctx.Name()).WithField("ignored", result.Ignored).Error(result.Error)
+		return result
+	}
+
+	if registry := registryFrom(ctx); registry != nil {
+		registry.set(r.name, strings.TrimSpace(capture.buf.String()), value)
+	}
+	return result
+}
+
+// value returns the registered value of the captured output.
+func (r *registered) value(capture *capture) (any, error) {
+	if capture.exceeded {
+		return nil, errors.Errorf("output exceeds %d bytes", r.limit)
+	}
+
+	var value any = strings.TrimSpace(capture.buf.String())
+	if r.json {
+		if err := json.Unmarshal(capture.buf.Bytes(), &value); err != nil {
+			return nil, errors.Wrap(err, "decode JSON")
+		}
+	}
+	return value, nil
+}
+
+// withRegistry returns a copy of ctx carrying a new registry if it does not carry one yet.
+func withRegistry(ctx context.Context) context.Context {
+	if registryFrom(ctx) != nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, registryKey{}, &registry{
+		raw:    map[string]string{},
+		values: map[string]any{},
+	})
+}
+
+func registryFrom(ctx context.Context) *registry {
+	registry, _ := ctx.Value(registryKey{}).(*registry)
+	return registry
+}
+
+func (r *registry) set(name, raw string, value any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.raw[name] = raw
+	r.values[name] = value
+}
+
+// done records the result of a command that has been run.
+func (r *registry) done(result Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.previous = result
+}
+
+// Values returns the template variables of the registered outputs and the result of the previous command.
+func (r *registry) Values() map[string]any {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	values := make(map[string]any, len(r.values)+1)
+	for k, v := range r.values {
+		values[k] = v
+	}
+	values["Previous"] = r.previous
+	return values
+}
+
+// Environ returns the environment variables of the registered outputs.
+func (r *registry) Environ() []string {
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var environ []string
+	for k, v := range r.raw {
+		environ = append(environ, k+"="+v)
+	}
+	slices.Sort(environ)
+	return environ
+}
+
+// captured returns w duplicating the output to the capture carried by ctx, if any.
+func captured(ctx context.Context, w io.Writer) io.Writer {
+	capture, ok := ctx.Value(captureKey{}).(*capture)
+	if !ok {
+		return w
+	}
+	return io.MultiWriter(w, capture)
+}
+
+// Write never fails so the command is not interrupted, the exceeding output is dropped.
+func (c *capture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := int64(len(p))
+	if remaining := c.limit - int64(c.buf.Len()); n > remaining {
+		c.exceeded = true
+		n = max(remaining, 0)
+	}
+
+	c.buf.Write(p[:n])
+	return len(p), nil
+}
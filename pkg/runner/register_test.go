@@ -0,0 +1,42 @@
+package runner
+
+import "testing"
+
+// An envContext is a testContext defining environment variables.
+type envContext struct {
+	testContext
+	environment map[string]string
+}
+
+func (c envContext) Environment() map[string]string { return c.environment }
+
+func TestRegisterName(t *testing.T) {
+	t.Setenv("REGISTER_TEST_DAEMON", "1")
+	ctx := envContext{environment: map[string]string{"TOKEN": "secret"}}
+
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{name: "OUTPUT", valid: true},
+		{name: "path", valid: true},
+		{name: "1OUTPUT"},
+		{name: "PATH"},
+		{name: "IFS"},
+		{name: "SHIGOTO_RUN_ID"},
+		{name: "TOKEN"},                // Environment of the baito.
+		{name: "REGISTER_TEST_DAEMON"}, // Environment of the daemon.
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Lookup(ctx, map[string]any{"exec": "true", "register": tt.name})
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
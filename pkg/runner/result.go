@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// A Result describes the outcome of a Runner.
+type Result struct {
+	Index      int
+	Label      string
+	Deferred   bool
+	Ignored    bool
+	Skipped    bool // The command has not been run because of its condition.
+	StartTime  time.Time
+	EndTime    time.Time
+	ExitCode   int           // The exit code of the process.
+	Status     int           // The status code of the HTTP response.
+	Output     int64         // The number of bytes written by the command.
+	Attempts   int           // The number of attempts of a retried command.
+	RetryAfter time.Duration // The delay requested by the command before another attempt (HTTP Retry-After).
+	Error      error
+	Results    []Result // The results of the wrapped runners.
+}
+
+// Duration returns the duration of the run.
+func (r Result) Duration() time.Duration {
+	return r.EndTime.Sub(r.StartTime)
+}
+
+// Failed returns true if the run has failed and its error is not ignored.
+func (r Result) Failed() bool {
+	return r.Error != nil && !r.Ignored
+}
+
+// Failure returns the result of the command that made the run fail.
+func (r Result) Failure() (Result, bool) {
+	if !r.Failed() {
+		return Result{}, false
+	}
+
+	// The error of the wrapper is the error of its last failed runner.
+	for i := len(r.Results) - 1; i >= 0; i-- {
+		if failure, ok := r.Results[i].Failure(); ok {
+			return failure, true
+		}
+	}
+	return r, true
+}
+
+// end completes the result of a run ended with the given error.
+func (r Result) end(err error) Result {
+	r.EndTime = time.Now()
+	r.Error = err
+	return r
+}
+
+// join returns the errors of the given results, prefixed by their index.
+func join(results []Result) error {
+	var errs []error
+	for _, result := range results {
+		if result.Error != nil {
+			errs = append(errs, fmt.Errorf("#%d: %w", result.Index, result.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,266 @@
+package runner
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/mdouchement/logger"
+	"github.com/pkg/errors"
+)
+
+// Backoff strategies.
+const (
+	// BackoffConstant waits the interval between the attempts.
+	BackoffConstant = "constant"
+	// BackoffExponential doubles the interval after each attempt.
+	BackoffExponential = "exponential"
+	// BackoffJitter waits a random duration up to the exponential interval.
+	BackoffJitter = "jitter"
+)
+
+type (
+	retry struct {
+		Runner
+		ctx    Context
+		policy *RetryPolicy
+		expose bool // Exposes the attempt to the runners (see Run).
+	}
+
+	// A RetryPolicy defines when and how a failed command is run again.
+	RetryPolicy struct {
+		attempts    int
+		interval    time.Duration
+		backoff     string
+		maxInterval time.Duration
+		exitCodes   []int
+		errors      []*regexp.Regexp
+	}
+
+	// A permanentError is a failure that is not fixed by running the command again.
+	permanentError struct {
+		error
+	}
+
+	// A retrier is a runner that defines its own retry policy.
+	retrier interface {
+		retryPolicy() *RetryPolicy
+	}
+)
+
+// ParseRetry parses a retry block:
+//
+//	retry:
+//	  attempts: 3
+//	  interval: 1s
+//	  backoff: constant
+//	  max_interval: 1m
+//	  on_exit_codes: [1, 255]
+//	  on_errors: ["connection (reset|refused)"]
+func ParseRetry(v any) (*RetryPolicy, error) {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("must be a map")
+	}
+
+	policy := &RetryPolicy{
+		attempts: 3,
+		interval: time.Second,
+		backoff:  BackoffConstant,
+	}
+
+	if v, ok := m["attempts"]; ok {
+		policy.attempts, ok = v.(int)
+		if !ok || policy.attempts < 1 {
+			return nil, errors.New("attempts must be a positive integer")
+		}
+	}
+
+	for key, d := range map[string]*time.Duration{"interval": &policy.interval, "max_interval": &policy.maxInterval} {
+		v, ok := m[key]
+		if !ok {
+			continue
+		}
+
+		s, ok := v.(string)
+		if !ok {
+			return nil, errors.Errorf("%s must be a string", key)
+		}
+
+		var err error
+		*d, err = time.ParseDuration(s)
+		if err != nil {
+			return nil, invalid(key, errors.Wrap(err, key))
+		}
+	}
+
+	if v, ok := m["backoff"]; ok {
+		policy.backoff, _ = v.(string)
+		switch policy.backoff {
+		case BackoffConstant, BackoffExponential, BackoffJitter:
+		default:
+			return nil, invalid("backoff", errors.Errorf("backoff: unsupported backoff '%v'", v))
+		}
+	}
+
+	if v, ok := m["on_exit_codes"]; ok {
+		codes, ok := v.([]any)
+		if !ok {
+			return nil, errors.New("on_exit_codes must be an array")
+		}
+
+		for _, code := range codes {
+			code, ok := code.(int)
+			if !ok {
+				return nil, errors.New("on_exit_codes must contain integers")
+			}
+			policy.exitCodes = append(policy.exitCodes, code)
+		}
+	}
+
+	if v, ok := m["on_errors"]; ok {
+		patterns, ok := v.([]any)
+		if !ok {
+			return nil, errors.New("on_errors must be an array")
+		}
+
+		for _, pattern := range patterns {
+			pattern, ok := pattern.(string)
+			if !ok {
+				return nil, errors.New("on_errors must contain strings")
+			}
+
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, invalid("on_errors", errors.Wrap(err, "on_errors"))
+			}
+			policy.errors = append(policy.errors, re)
+		}
+	}
+
+	return policy, nil
+}
+
+// Retry runs again the given runner when it fails according to the given policy.
+// The attempt is exposed to the runners as a template variable and an environment variable (see Run).
+// A nil policy means no retry.
+func Retry(ctx Context, runner Runner, policy *RetryPolicy) Runner {
+	if policy == nil {
+		return runner
+	}
+
+	return &retry{
+		Runner: runner,
+		ctx:    ctx,
+		policy: policy,
+		expose: true,
+	}
+}
+
+func (r *retry) Run(ctx context.Context) Result {
+	log := logger.LogWith(ctx)
+	run := RunFrom(ctx)
+
+	for attempt := 1; ; attempt++ {
+		ctx := logger.WithLogger(ctx, log.WithField("attempt", attempt))
+		if r.expose {
+			run.Attempt = attempt
+			ctx = WithRun(ctx, run)
+		}
+
+		result := r.Runner.Run(ctx)
+		result.Attempts = attempt
+
+		if result.Error == nil || attempt >= r.policy.attempts || !r.policy.retryable(result) || ctx.Err() != nil {
+			return result
+		}
+
+		label := result.Label
+		if label == "" {
+			label = r.ctx.Name()
+		}
+
+		delay := r.policy.delay(attempt, result)
+		log.WithPrefixf("[%s]", r.ctx.Name()).WithField("attempt", attempt).Warnf(`Retrying "%s" in %s (attempt %d/%d)`, label, delay, attempt+1, r.policy.attempts)
+
+		select {
+		case <-ctx.Done():
+			return result
+		case <-time.After(delay):
+		}
+	}
+}
+
+// permanent marks the given error as not retryable.
+func permanent(err error) error {
+	return &permanentError{error: err}
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.error
+}
+
+// retryable returns true if the failed result matches the conditions of the policy.
+// Without condition, all the failures are retried except the permanent ones.
+func (p *RetryPolicy) retryable(result Result) bool {
+	failure := cause(result)
+
+	var permanent *permanentError
+	if errors.As(result.Error, &permanent) || errors.As(failure.Error, &permanent) {
+		return false
+	}
+
+	// The exit code of a chain is the one of its failed command.
+	result.ExitCode = failure.ExitCode
+
+	if len(p.exitCodes) == 0 && len(p.errors) == 0 {
+		return true
+	}
+
+	if slices.Contains(p.exitCodes, result.ExitCode) {
+		return true
+	}
+
+	for _, re := range p.errors {
+		if re.MatchString(result.Error.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the duration to wait after the given attempt.
+// The delay requested by the failed command (e.g. HTTP Retry-After) is honored if it is longer.
+func (p *RetryPolicy) delay(attempt int, result Result) time.Duration {
+	delay := p.interval
+	if p.backoff != BackoffConstant {
+		delay = time.Duration(float64(p.interval) * math.Pow(2, float64(attempt-1)))
+	}
+
+	if p.maxInterval > 0 && (delay > p.maxInterval || delay < 0) {
+		delay = p.maxInterval
+	}
+
+	if p.backoff == BackoffJitter && delay > 0 {
+		delay = rand.N(delay)
+	}
+
+	if failure := cause(result); failure.RetryAfter > delay {
+		delay = failure.RetryAfter
+	}
+	return delay
+}
+
+// cause returns the result of the command that made the run fail (see Result.Failure).
+// The errors of a command ignoring them are included since the command is still retried.
+func cause(result Result) Result {
+	for i := len(result.Results) - 1; i >= 0; i-- {
+		if r := result.Results[i]; r.Failed() || (result.Ignored && r.Error != nil) {
+			return cause(r)
+		}
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+package runner
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mdouchement/logger"
+	"github.com/mdouchement/shigoto/pkg/io"
+)
+
+// A testContext is the Context of the runners built by the tests.
+type testContext struct{}
+
+func (testContext) Name() string                         { return "test" }
+func (testContext) Environment() map[string]string       { return nil }
+func (testContext) ExpandAll(s string) string            { return s }
+func (testContext) ExpandEnv(s string) string            { return s }
+func (testContext) ExpandVariables(s string) string      { return s }
+func (testContext) ExpandTilde(s string) (string, error) { return s, nil }
+func (testContext) Variables() map[string]string         { return nil }
+func (testContext) Workdir() string                      { return "" }
+func (testContext) LogsFile() io.WriteSyncer             { return nil }
+func (testContext) HTTP() map[string]any                 { return nil }
+
+func TestRetryHTTPStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		payload  map[string]any
+		attempts int
+		failed   bool
+	}{
+		{
+			name:     "client error",
+			status:   nethttp.StatusNotFound,
+			payload:  map[string]any{},
+			attempts: 1,
+			failed:   true,
+		},
+		{
+			name:     "ignored client error",
+			status:   nethttp.StatusNotFound,
+			payload:  map[string]any{"ignore_error": true},
+			attempts: 1,
+		},
+		{
+			name:     "ignored client error with a retry block",
+			status:   nethttp.StatusNotFound,
+			payload:  map[string]any{"ignore_error": true, "label": "probe", "timeout": "10s", "retry": map[string]any{"attempts": 3, "interval": "1ms"}},
+			attempts: 1,
+		},
+		{
+			name:     "server error",
+			status:   nethttp.StatusServiceUnavailable,
+			payload:  map[string]any{"retry_interval": "1ms"},
+			attempts: 4,
+			failed:   true,
+		},
+		{
+			name:     "ignored server error",
+			status:   nethttp.StatusServiceUnavailable,
+			payload:  map[string]any{"ignore_error": true, "retry_interval": "1ms"},
+			attempts: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int64
+			server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, _ *nethttp.Request) {
+				hits.Add(1)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			payload := map[string]any{"http": server.URL}
+			for k, v := range tt.payload {
+				payload[k] = v
+			}
+
+			runner, err := Lookup(testContext{}, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result := runner.Run(logger.WithLogger(context.Background(), logger.NewNullLogger()))
+			if result.Error == nil {
+				t.Fatal("expected an error")
+			}
+			if result.Failed() != tt.failed {
+				t.Errorf("failed: got %t, expected %t", result.Failed(), tt.failed)
+			}
+			if n := hits.Load(); n != int64(tt.attempts) {
+				t.Errorf("requests: got %d, expected %d", n, tt.attempts)
+			}
+		})
+	}
+}
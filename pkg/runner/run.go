@@ -0,0 +1,243 @@
+package runner
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mdouchement/logger"
+	"github.com/mdouchement/shigoto/pkg/templater"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A Run describes the current run of a baito.
+	// It's exposed to the commands as template variables and `SHIGOTO_*` environment variables.
+	Run struct {
+		ID            string
+		Shigoto       string // The file of the baito.
+		Baito         string
+		ScheduledTime time.Time
+		StartTime     time.Time
+		Attempt       int
+	}
+
+	// A runContext is the Context of a runner built for a given run.
+	runContext struct {
+		Context
+		run       Run
+		registry  *registry
+		iteration *iteration
+	}
+
+	// A rendered runner is built at each run from its templated payload.
+	rendered struct {
+		ctx         Context
+		payload     map[string]any
+		deferrable  bool
+		ignoreError bool
+	}
+
+	runKey struct{}
+)
+
+// WithRun returns a copy of ctx carrying the given run.
+func WithRun(ctx context.Context, run Run) context.Context {
+	return context.WithValue(ctx, runKey{}, run)
+}
+
+// RunFrom returns the run carried by ctx.
+func RunFrom(ctx context.Context) Run {
+	run, _ := ctx.Value(runKey{}).(Run)
+	return run
+}
+
+// Values returns the template variables of the run.
+func (r Run) Values() map[string]any {
+	return map[string]any{
+		"RunID":         r.ID,
+		"ShigotoFile":   r.Shigoto,
+		"BaitoName":     r.Baito,
+		"ScheduledTime": r.ScheduledTime,
+		"StartTime":     r.StartTime,
+		"Attempt":       r.Attempt,
+	}
+}
+
+// Environ returns the environment variables of the run.
+// The times are formatted according to RFC 3339.
+func (r Run) Environ() []string {
+	return []string{
+		"SHIGOTO_RUN_ID=" + r.ID,
+		"SHIGOTO_FILE=" + r.Shigoto,
+		"SHIGOTO_BAITO_NAME=" + r.Baito,
+		"SHIGOTO_SCHEDULED_TIME=" + r.ScheduledTime.Format(time.RFC3339),
+		"SHIGOTO_START_TIME=" + r.StartTime.Format(time.RFC3339),
+		"SHIGOTO_ATTEMPT=" + strconv.Itoa(r.Attempt),
+	}
+}
+
+// runEnviron returns the environment variables of the run carried by ctx, its registered outputs and the current item of a loop.
+func runEnviron(ctx context.Context) []string {
+	environ := append(RunFrom(ctx).Environ(), registryFrom(ctx).Environ()...)
+	return append(environ, iterationFrom(ctx).Environ()...)
+}
+
+// newRunContext returns the Context of a runner built for the run carried by ctx.
+func newRunContext(c Context, ctx context.Context) *runContext {
+	return &runContext{
+		Context:   c,
+		run:       RunFrom(ctx),
+		registry:  registryFrom(ctx),
+		iteration: iterationFrom(ctx),
+	}
+}
+
+// Values returns the registered outputs, the template variables of the run and the current item of a loop.
+func (c *runContext) Values() map[string]any {
+	values := c.registry.Values()
+	if values == nil {
+		values = map[string]any{}
+	}
+
+	for k, v := range c.run.Values() {
+		values[k] = v
+	}
+	for k, v := range c.iteration.Values() {
+		values[k] = v
+	}
+	return values
+}
+
+// ExpandVariables returns the given string as is, the payload being already rendered by build.
+func (c *runContext) ExpandVariables(str string) string {
+	return str
+}
+
+// ExpandAll only replaces the environment variables, the payload being already rendered by build.
+// A rendered value is not rendered again (e.g. a registered output containing `{{`).
+func (c *runContext) ExpandAll(str string) string {
+	return c.ExpandEnv(str)
+}
+
+// newRendered returns a runner built at each run from the given payload, the given keys using templates.
+// The runner is built once with the templates as is so the payload is validated when loaded.
+// The templated fields may use values only known at run time (e.g. registered outputs), so only their syntax is checked.
+func newRendered(ctx Context, payload map[string]any, keys []string) (Runner, error) {
+	for _, k := range keys {
+		if err := parse(payload[k]); err != nil {
+			return nil, errors.Wrap(err, k)
+		}
+	}
+
+	r := &rendered{
+		ctx:     ctx,
+		payload: payload,
+	}
+	_, r.deferrable = payload["defer"]
+	r.ignoreError, _ = payload["ignore_error"].(bool)
+
+	if err := r.check(keys); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// check builds the runner from the payload with its templates as is.
+// The errors caused by the values of the templated fields are ignored, the values being only known at run time.
+func (r *rendered) check(keys []string) error {
+	_, err := lookup(&runContext{Context: r.ctx}, r.payload)
+
+	var ferr *fieldError
+	if errors.As(err, &ferr) && slices.Contains(keys, ferr.field) {
+		return nil
+	}
+	return err
+}
+
+func (r *rendered) IsDeferrable() bool {
+	return r.deferrable
+}
+
+func (r *rendered) IsErrorIgnored() bool {
+	return r.ignoreError
+}
+
+func (r *rendered) Run(ctx context.Context) Result {
+	runners.Lock()
+	runner, err := build(newRunContext(r.ctx, ctx), r.payload)
+	runners.Unlock()
+
+	if err != nil {
+		err = errors.Wrap(err, "template")
+		logger.LogWith(ctx).WithPrefixf("[%s]", r.ctx.Name()).WithField("ignored", r.ignoreError).Error(err)
+
+		result := Result{
+			Deferred:  r.deferrable,
+			Ignored:   r.ignoreError,
+			StartTime: time.Now(),
+		}
+		return result.end(err)
+	}
+
+	return runner.Run(ctx)
+}
+
+// templates returns the keys of the payload whose strings use templates, including the ones nested in maps and lists (e.g. headers).
+// The nested commands (e.g. defer) handle their own templates.
+func templates(payload map[string]any) []string {
+	var found []string
+	for k, v := range payload {
+		if _, ok := subcommands[k]; ok {
+			continue
+		}
+		if templated(v) {
+			found = append(found, k)
+		}
+	}
+	return found
+}
+
+// templated returns true when the given string or one of the strings nested in the given maps and lists uses templates.
+func templated(v any) bool {
+	switch v := v.(type) {
+	case string:
+		return strings.Contains(v, "{{")
+	case map[string]any:
+		for _, v := range v {
+			if templated(v) {
+				return true
+			}
+		}
+	case []any:
+		for _, v := range v {
+			if templated(v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parse checks the syntax of the given string or of the strings nested in the given maps and lists.
+func parse(v any) error {
+	switch v := v.(type) {
+	case string:
+		return templater.Parse(v)
+	case map[string]any:
+		for k, v := range v {
+			if err := parse(v); err != nil {
+				return errors.Wrap(err, k)
+			}
+		}
+	case []any:
+		for i, v := range v {
+			if err := parse(v); err != nil {
+				return errors.Wrapf(err, "[%d]", i)
+			}
+		}
+	}
+	return nil
+}
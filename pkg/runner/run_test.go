@@ -0,0 +1,182 @@
+package runner
+
+import (
+	"context"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mdouchement/logger"
+	"github.com/mdouchement/shigoto/pkg/templater"
+)
+
+// A templatingContext renders the templates of the expanded strings like the context of a baito.
+type templatingContext struct {
+	testContext
+}
+
+func (c templatingContext) ExpandAll(s string) string {
+	return templater.New(c).Replace(s)
+}
+
+func (c templatingContext) ExpandVariables(s string) string {
+	return templater.New(c).Replace(s)
+}
+
+func TestRenderNestedFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]any
+		header  string
+		failed  bool
+	}{
+		{
+			name:    "header",
+			payload: map[string]any{"headers": map[string]any{"X-Run": "{{.RunID}}"}},
+			header:  "run-1",
+		},
+		{
+			name:    "basic auth",
+			payload: map[string]any{"basic_auth": map[string]any{"username": "{{.BaitoName}}", "password": "secret"}},
+			header:  "Basic YmFpdG86c2VjcmV0",
+		},
+		{
+			name:    "missing key",
+			payload: map[string]any{"headers": map[string]any{"X-Run": "{{.Missing}}"}},
+			failed:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var header string
+			server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+				header = r.Header.Get("X-Run")
+				if header == "" {
+					header = r.Header.Get("Authorization")
+				}
+			}))
+			defer server.Close()
+
+			payload := map[string]any{"http": server.URL}
+			for k, v := range tt.payload {
+				payload[k] = v
+			}
+
+			runner, err := Lookup(testContext{}, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := logger.WithLogger(context.Background(), logger.NewNullLogger())
+			result := runner.Run(WithRun(ctx, Run{ID: "run-1", Baito: "baito"}))
+			if result.Failed() != tt.failed {
+				t.Fatalf("failed: got %t (%v), expected %t", result.Failed(), result.Error, tt.failed)
+			}
+			if header != tt.header {
+				t.Errorf("header: got %q, expected %q", header, tt.header)
+			}
+		})
+	}
+}
+
+func TestRenderOnce(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		output  string
+		echoed  string
+		failed  bool
+	}{
+		{
+			name:    "template in an output",
+			command: "echo {{.OUT}}",
+			output:  `{{env "HOME"}}`,
+			echoed:  `{{env "HOME"}}`,
+		},
+		{
+			name:    "invalid template in an output",
+			command: "echo {{.OUT}}",
+			output:  "{{x",
+			echoed:  "{{x",
+		},
+		{
+			name:    "empty command",
+			command: "{{.OUT}}",
+			failed:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, err := Lookup(templatingContext{}, map[string]any{"exec": tt.command, "register": "ECHOED"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := withRegistry(logger.WithLogger(context.Background(), logger.NewNullLogger()))
+			registryFrom(ctx).set("OUT", tt.output, tt.output)
+
+			result := runner.Run(ctx)
+			if result.Failed() != tt.failed {
+				t.Fatalf("failed: got %t (%v), expected %t", result.Failed(), result.Error, tt.failed)
+			}
+			if echoed := registryFrom(ctx).raw["ECHOED"]; echoed != tt.echoed {
+				t.Errorf("output: got %q, expected %q", echoed, tt.echoed)
+			}
+		})
+	}
+}
+
+func TestRenderedCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload map[string]any
+		valid   bool
+	}{
+		{
+			name:    "templated duration",
+			payload: map[string]any{"http": "https://example.com", "timeout": "{{.TIMEOUT}}"},
+			valid:   true,
+		},
+		{
+			name:    "templated method",
+			payload: map[string]any{"http": "https://example.com", "method": "{{.METHOD}}"},
+			valid:   true,
+		},
+		{
+			name:    "templated script",
+			payload: map[string]any{"sh": "{{.SCRIPT}} ("},
+			valid:   true,
+		},
+		{
+			name:    "templated retry",
+			payload: map[string]any{"exec": "true", "retry": map[string]any{"interval": "{{.INTERVAL}}"}},
+			valid:   true,
+		},
+		{
+			name:    "invalid field next to a templated one",
+			payload: map[string]any{"http": "https://example.com/{{.RunID}}", "timeout": "soon"},
+		},
+		{
+			name:    "templated body with a body file",
+			payload: map[string]any{"http": "https://example.com", "body": "{{.B}}", "body_file": "/tmp/body"},
+		},
+		{
+			name:    "templated command naming several runners",
+			payload: map[string]any{"exec": "echo {{.RunID}}", "sh": "true"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Lookup(testContext{}, tt.payload)
+			if tt.valid && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -1,23 +1,27 @@
 package runner
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/mdouchement/logger"
 	"github.com/mdouchement/shigoto/pkg/io"
+	"github.com/mdouchement/shigoto/pkg/templater"
 )
 
 type (
 	// A Runner is the action to be executed.
+	// Run stops as soon as possible when ctx is cancelled and logs with the logger carried by ctx (see logger.WithLogger).
+	// A Runner holds no state between runs so it can be run concurrently.
 	Runner interface {
-		Run()
+		Run(ctx context.Context) Result
 		IsDeferrable() bool
-		Error() error
 		IsErrorIgnored() bool
-		AttachLogger(l logger.Logger)
 	}
 
 	// A Context carries the context of a Runner.
@@ -25,11 +29,20 @@ type (
 		Name() string
 		Environment() map[string]string
 		ExpandAll(string) string
+		ExpandEnv(string) string
 		ExpandVariables(string) string
 		ExpandTilde(string) (string, error)
 		Variables() map[string]string
 		Workdir() string
 		LogsFile() io.WriteSyncer
+		HTTP() map[string]any // The file-level settings of the HTTP requests.
+	}
+
+	// A fieldError is an error caused by the value of a field of a payload.
+	// The value of a templated field is only known at run time so its errors are ignored when the payload is loaded (see rendered.check).
+	fieldError struct {
+		field string
+		err   error
 	}
 
 	factory struct {
@@ -41,6 +54,12 @@ type (
 var (
 	runners factory
 	once    sync.Once
+
+	// The keys of the payloads holding nested commands.
+	subcommands = map[string]struct{}{
+		"defer":    {},
+		"parallel": {},
+	}
 )
 
 // GenerateID creates a new ID.
@@ -64,19 +83,116 @@ func Register(name string, fn func(ctx Context, payload map[string]any) (Runner,
 }
 
 // Lookup returns a new runner according given payload.
+// The strings of the payload are templated with the variables of the context.
+// A payload using templates is rendered at each run with the values of the run (see Run).
+// A payload with a `for_each` field is run once per item of its list (see newForEach).
+// A payload with a `when` field is run only when its condition is true.
 func Lookup(ctx Context, payload map[string]any) (Runner, error) {
 	runners.Lock()
 	defer runners.Unlock()
 
+	return newCommand(ctx, payload)
+}
+
+// newCommand returns the runner of a command of a taskfile.
+func newCommand(ctx Context, payload map[string]any) (Runner, error) {
+	if _, ok := payload["for_each"]; ok {
+		return newForEach(ctx, payload)
+	}
+
+	if _, ok := payload["when"]; ok {
+		return newConditional(ctx, payload)
+	}
+
+	if templates := templates(payload); len(templates) > 0 {
+		return newRendered(ctx, payload, templates)
+	}
+
+	return build(&runContext{Context: ctx}, payload)
+}
+
+// build templates the given payload and returns its runner.
+func build(ctx Context, payload map[string]any) (Runner, error) {
+	payload, err := render(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
 	return lookup(ctx, payload)
 }
 
+// render templates the strings of the payload, including the ones nested in its maps and lists.
+// The nested commands (see subcommands) are left as is, they handle their own templates.
+// A missing key is an error instead of being rendered as `<no value>`.
+func render(ctx Context, payload map[string]any) (map[string]any, error) {
+	templater := templater.NewStrict(ctx)
+
+	rendered := make(map[string]any, len(payload))
+	for k, v := range payload {
+		if _, ok := subcommands[k]; ok {
+			rendered[k] = v
+			continue
+		}
+		rendered[k] = templater.ReplaceAny(v)
+	}
+	return rendered, templater.Err()
+}
+
 func lookup(ctx Context, payload map[string]any) (Runner, error) {
+	name, err := runnerName(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	runner, err := runners.runners[name](ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return options(ctx, name, runner, payload)
+}
+
+// runnerName returns the name of the runner of the payload, the only key of the payload being a runner.
+func runnerName(payload map[string]any) (string, error) {
+	var names []string
 	for k := range payload {
-		if create, ok := runners.runners[k]; ok {
-			return create(ctx, payload)
+		if _, ok := runners.runners[k]; ok {
+			names = append(names, k)
 		}
 	}
 
-	return nil, errors.New("runner not found")
+	switch len(names) {
+	case 0:
+		return "", errors.New("runner not found")
+	case 1:
+		return names[0], nil
+	default:
+		slices.Sort(names)
+		return "", fmt.Errorf("ambiguous runner: %s", strings.Join(names, ", "))
+	}
+}
+
+// invalid returns an error caused by the value of the given field of a payload.
+func invalid(field string, err error) error {
+	return &fieldError{
+		field: field,
+		err:   err,
+	}
+}
+
+// nested returns an error caused by the value of the given field when err is caused by the value of one of its nested fields.
+func nested(field string, err error) error {
+	var ferr *fieldError
+	if errors.As(err, &ferr) {
+		return invalid(field, err)
+	}
+	return err
+}
+
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
 }
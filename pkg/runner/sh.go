@@ -2,12 +2,12 @@ package runner
 
 import (
 	"context"
-	"fmt"
 	"io"
 	"os"
 	"strings"
 	"time"
 
+	pkgio "github.com/mdouchement/shigoto/pkg/io"
 	"github.com/pkg/errors"
 	"mvdan.cc/sh/v3/expand"
 	"mvdan.cc/sh/v3/interp"
@@ -19,59 +19,64 @@ type sh struct {
 
 	script   string
 	file     *syntax.File
-	redirect *os.File
+	redirect string
+	expect   *expectation
 }
 
-func (r *sh) Run() {
-	r.Lock()
-	defer r.Unlock()
-
-	// Reset previous state
-	r.err = nil
-
-	//
-
-	start := time.Now()
-	logger := r.log.WithPrefixf("[%s]", r.ctx.Name()).WithField("id", GenerateID())
-	logger.Info(strings.Split(r.script, "\n")[0] + "...")
-	if r.redirect != nil {
-		defer r.redirect.Sync()
-	}
+func (r *sh) Run(ctx context.Context) Result {
+	result := r.result()
+	logger := r.logger(ctx)
+	logger.Info(r.label + "...")
 	if r.ctx.LogsFile() != nil {
 		defer r.ctx.LogsFile().Sync()
 	}
 
-	shell, err := r.buildShell()
+	redirect, err := redirection(r.redirect)
 	if err != nil {
-		r.err = err
-		logger.WithField("elapsed_time", time.Since(start)).WithField("ignored", r.ignoreError).Error(err)
-		return
+		err = errors.Wrap(err, "could not create logs redirection file")
+		logger.WithField("elapsed_time", time.Since(result.StartTime)).WithField("ignored", r.ignoreError).Error(err)
+		return result.end(err)
 	}
-
-	if err := shell.Run(context.Background(), r.file); err != nil {
-		r.err = err
-		logger.WithField("elapsed_time", time.Since(start)).WithField("ignored", r.ignoreError).Error(err)
-		return
+	if redirect != nil {
+		defer redirect.Close()
 	}
 
-	logger.WithField("elapsed_time", time.Since(start)).Info("finished")
-}
-
-func (r *sh) buildShell() (*interp.Runner, error) {
-	environ := os.Environ()
-	for k, v := range r.ctx.Environment() {
-		environ = append(environ, fmt.Sprintf("%s=%s", k, v))
+	var stdout, stderr io.Writer = os.Stdout, os.Stderr
+	if r.ctx.LogsFile() != nil {
+		stdout, stderr = r.ctx.LogsFile(), r.ctx.LogsFile()
 	}
+	if redirect != nil {
+		stdout, stderr = redirect, redirect
+	}
+	matcher := r.expect.matcher()
+	stdout, flushStdout := matcher.tee(captured(ctx, stdout))
+	stderr, flushStderr := matcher.tee(stderr)
+	counters := []*pkgio.Counter{pkgio.NewCounter(stdout), pkgio.NewCounter(stderr)}
 
-	stdio := interp.StdIO(os.Stdin, os.Stdout, os.Stderr)
-	if r.ctx.LogsFile() != nil {
-		stdio = interp.StdIO(os.Stdin, r.ctx.LogsFile(), r.ctx.LogsFile())
+	shell, err := r.buildShell(r.environ(ctx), counters[0], counters[1])
+	if err != nil {
+		logger.WithField("elapsed_time", time.Since(result.StartTime)).WithField("ignored", r.ignoreError).Error(err)
+		return result.end(err)
 	}
 
-	if r.redirect != nil {
-		stdio = interp.StdIO(os.Stdin, r.redirect, r.redirect)
+	// The interpreter stops and kills the running programs when the context is done.
+	err = shell.Run(ctx, r.file)
+	flushStdout()
+	flushStderr()
+	result.Output = counters[0].Count() + counters[1].Count()
+	if status, ok := interp.IsExitStatus(err); ok {
+		result.ExitCode = int(status)
+	}
+	if err = r.expect.check(result.ExitCode, err, matcher); err != nil {
+		logger.WithField("elapsed_time", time.Since(result.StartTime)).WithField("exit_code", result.ExitCode).WithField("ignored", r.ignoreError).Error(err)
+		return result.end(err)
 	}
 
+	logger.WithField("elapsed_time", time.Since(result.StartTime)).Info("finished")
+	return result.end(nil)
+}
+
+func (r *sh) buildShell(environ []string, stdout, stderr io.Writer) (*interp.Runner, error) {
 	return interp.New(
 		interp.Dir(r.ctx.Workdir()),
 		interp.Env(expand.ListEnviron(environ...)),
@@ -83,7 +88,7 @@ func (r *sh) buildShell() (*interp.Runner, error) {
 			return interp.DefaultOpenHandler()(ctx, path, flag, perm)
 		}),
 
-		stdio,
+		interp.StdIO(os.Stdin, stdout, stderr),
 	)
 }
 
@@ -105,11 +110,12 @@ func init() {
 			return nil, errors.New("taskfile: sh: command must be a string")
 		}
 		executor.script = executor.ctx.ExpandVariables(executor.script)
+		executor.label = strings.Split(executor.script, "\n")[0]
 
 		var err error
 		executor.file, err = syntax.NewParser().Parse(strings.NewReader(executor.script), "")
 		if err != nil {
-			return nil, errors.Wrap(err, "taskfile: sh: parse script")
+			return nil, invalid("sh", errors.Wrap(err, "taskfile: sh: parse script"))
 		}
 
 		// Ignore error
@@ -122,20 +128,19 @@ func init() {
 			executor.ignoreError = b
 		}
 
+		// Success of the process
+		executor.expect, err = parseExpectation("sh", payload)
+		if err != nil {
+			return nil, err
+		}
+
 		// Redirect command stdout/stderr to a file
 		if v, ok := payload["redirect"]; ok {
 			path, ok := v.(string)
 			if !ok {
 				return nil, errors.New("taskfile: sh: redirect field must be a string")
 			}
-			path = executor.ctx.ExpandAll(path)
-
-			f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
-			if err != nil {
-				return nil, errors.Wrap(err, "could not create logs redirection file")
-			}
-
-			executor.redirect = f
+			executor.redirect = executor.ctx.ExpandAll(path)
 		}
 
 		return executor, nil
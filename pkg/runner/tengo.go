@@ -1,6 +1,10 @@
 package runner
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
 	"os"
 	"strings"
 	"time"
@@ -9,6 +13,8 @@ import (
 	"github.com/d5/tengo/v2/stdlib"
 	"github.com/mdouchement/ldt/pkg/tengolib"
 	"github.com/mdouchement/logger"
+	pkgio "github.com/mdouchement/shigoto/pkg/io"
+	"github.com/mdouchement/shigoto/pkg/templater"
 	"github.com/pkg/errors"
 )
 
@@ -19,17 +25,9 @@ type tengo struct {
 	redirect *os.File
 }
 
-func (r *tengo) Run() {
-	r.Lock()
-	defer r.Unlock()
-
-	// Reset previous state
-	r.err = nil
-
-	//
-
-	start := time.Now()
-	logger := r.log.WithPrefixf("[%s]", r.ctx.Name()).WithField("id", GenerateID())
+func (r *tengo) Run(ctx context.Context) Result {
+	result := r.result()
+	logger := r.logger(ctx)
 	logger.Info("Running tengo script")
 
 	// Load modules
@@ -38,17 +36,85 @@ func (r *tengo) Run() {
 	modules.AddBuiltinModule("shigoto", map[string]tengopkg.Object{
 		"logger": withlogger(logger),
 	})
+	modules.AddBuiltinModule("os", r.osModule(modules, r.environ(ctx)))
+	stdout := pkgio.NewCounter(captured(ctx, os.Stdout))
+	modules.AddBuiltinModule("fmt", r.fmtModule(modules, stdout))
 
 	// Compile source code
 	script := tengopkg.NewScript([]byte(r.src))
 	script.SetImports(modules)
-	if _, err := script.Run(); err != nil {
-		r.err = err
-		logger.WithField("elapsed_time", time.Since(start)).WithField("ignored", r.ignoreError).Error(err)
-		return
+	// The virtual machine is aborted when the context is done.
+	_, err := script.RunContext(ctx)
+	result.Output = stdout.Count()
+	if err != nil {
+		logger.WithField("elapsed_time", time.Since(result.StartTime)).WithField("ignored", r.ignoreError).Error(err)
+		return result.end(err)
+	}
+
+	logger.WithField("elapsed_time", time.Since(result.StartTime)).Info("finished")
+	return result.end(nil)
+}
+
+// osModule returns the os module reading the given environment instead of the daemon one.
+func (r *tengo) osModule(modules *tengopkg.ModuleMap, environ []string) map[string]tengopkg.Object {
+	env := make(map[string]string, len(environ))
+	for _, e := range environ {
+		k, v, _ := strings.Cut(e, "=")
+		env[k] = v
 	}
 
-	logger.WithField("elapsed_time", time.Since(start)).Info("finished")
+	attrs := maps.Clone(modules.GetBuiltinModule("os").Attrs) // The attributes are shared by all the scripts.
+	attrs["environ"] = &tengopkg.UserFunction{
+		Name: "environ",
+		Value: stdlib.FuncARSs(func() []string {
+			return environ
+		}),
+	}
+	attrs["getenv"] = &tengopkg.UserFunction{
+		Name: "getenv",
+		Value: stdlib.FuncASRS(func(k string) string {
+			return env[k]
+		}),
+	}
+	return attrs
+}
+
+// fmtModule returns the fmt module printing to the given writer instead of the standard output.
+func (r *tengo) fmtModule(modules *tengopkg.ModuleMap, w io.Writer) map[string]tengopkg.Object {
+	print := func(args []tengopkg.Object, suffix ...any) (tengopkg.Object, error) {
+		var a []any
+		for _, arg := range args {
+			s, _ := tengopkg.ToString(arg)
+			a = append(a, s)
+		}
+		fmt.Fprint(w, append(a, suffix...)...)
+		return nil, nil
+	}
+
+	attrs := maps.Clone(modules.GetBuiltinModule("fmt").Attrs) // The attributes are shared by all the scripts.
+	attrs["print"] = &tengopkg.UserFunction{
+		Name: "print",
+		Value: func(args ...tengopkg.Object) (tengopkg.Object, error) {
+			return print(args)
+		},
+	}
+	attrs["println"] = &tengopkg.UserFunction{
+		Name: "println",
+		Value: func(args ...tengopkg.Object) (tengopkg.Object, error) {
+			return print(args, "\n")
+		},
+	}
+	attrs["printf"] = &tengopkg.UserFunction{
+		Name: "printf",
+		Value: func(args ...tengopkg.Object) (tengopkg.Object, error) {
+			s, err := attrs["sprintf"].(*tengopkg.UserFunction).Value(args...)
+			if err != nil {
+				return nil, err
+			}
+			return print([]tengopkg.Object{s})
+		},
+	}
+	return attrs
 }
 
 func init() {
@@ -68,21 +134,30 @@ func init() {
 		if !ok {
 			return nil, errors.New("taskfile: tengo: src must be a string")
 		}
+		executor.label = "tengo script"
 
 		// Check if src is a file and not plain code.
 		if strings.HasSuffix(strings.TrimSpace(executor.src), ".tengo") || strings.HasSuffix(strings.TrimSpace(executor.src), ".tgo") {
 			executor.src = executor.ctx.ExpandAll(executor.src)
 			var err error
 			executor.src, err = executor.ctx.ExpandTilde(executor.src)
-			if !ok {
-				return nil, errors.Wrap(err, "taskfile: tengo: expand filename")
+			if err != nil {
+				return nil, invalid("tengo", errors.Wrap(err, "taskfile: tengo: expand filename"))
 			}
 
+			executor.label = executor.src
+
 			src, err := os.ReadFile(executor.src)
 			if err != nil {
+				return nil, invalid("tengo", errors.Wrap(err, "taskfile: tengo: file"))
+			}
+
+			// The content of the file is not part of the payload so it is rendered here.
+			templater := templater.New(ctx)
+			executor.src = templater.Replace(string(src))
+			if err = templater.Err(); err != nil {
 				return nil, errors.Wrap(err, "taskfile: tengo: file")
 			}
-			executor.src = string(src)
 		}
 
 		executor.src = executor.ctx.ExpandAll(executor.src)
@@ -0,0 +1,261 @@
+package runner
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	nethttp "net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultMaxRedirects is the default number of redirects followed by an HTTP request.
+	DefaultMaxRedirects = 10
+	// DefaultConnectTimeout is the default maximum duration to connect to an HTTP server.
+	DefaultConnectTimeout = 30 * time.Second
+)
+
+type (
+	// A transport is the configuration of the client sending an HTTP request.
+	// It's comparable so the commands with the same configuration share the same client (see clients).
+	transport struct {
+		connectTimeout time.Duration
+		timeout        time.Duration
+		caFile         string
+		certFile       string
+		keyFile        string
+		insecure       bool
+		proxy          string
+		maxRedirects   int
+		http2          bool
+	}
+
+	// A cachedClient is a client shared by the runs of the commands with the same transport.
+	cachedClient struct {
+		client *nethttp.Client
+		stamp  string // The version of the certificate files read by the client.
+	}
+)
+
+// clients are the clients of the transports, created at their first request.
+// A client is created again when its certificate files change, so a renewed certificate is used without reloading the files.
+var clients = struct {
+	sync.Mutex
+	m map[transport]*cachedClient
+}{
+	m: map[transport]*cachedClient{},
+}
+
+// parseTransport parses the transport fields of the payload, the file-level settings (see Context.HTTP) being the default values:
+//
+//	connect_timeout: 5s
+//	request_timeout: 30s
+//	ca_file: /etc/pki/internal-ca.pem
+//	cert_file: /etc/pki/client.pem
+//	key_file: /etc/pki/client-key.pem
+//	insecure_skip_verify: false
+//	proxy: http://proxy.example.com:3128
+//	max_redirects: 10
+//	http2: true
+func parseTransport(ctx Context, payload map[string]any) (transport, error) {
+	t := transport{
+		connectTimeout: DefaultConnectTimeout,
+		maxRedirects:   DefaultMaxRedirects,
+		http2:          true,
+	}
+
+	// The file-level settings are not part of the payload so they are rendered here.
+	settings, err := render(ctx, ctx.HTTP())
+	if err != nil {
+		return t, errors.Wrap(err, "taskfile: http")
+	}
+	for _, k := range []string{"connect_timeout", "request_timeout", "ca_file", "cert_file", "key_file", "insecure_skip_verify", "proxy", "max_redirects", "http2"} {
+		if v, ok := payload[k]; ok {
+			settings[k] = v
+		}
+	}
+
+	for key, d := range map[string]*time.Duration{"connect_timeout": &t.connectTimeout, "request_timeout": &t.timeout} {
+		v, ok := settings[key]
+		if !ok {
+			continue
+		}
+
+		s, ok := v.(string)
+		if !ok {
+			return t, errors.Errorf("taskfile: http: %s must be a string", key)
+		}
+
+		var err error
+		*d, err = time.ParseDuration(s)
+		if err != nil {
+			return t, invalid(key, errors.Wrapf(err, "taskfile: http: %s", key))
+		}
+	}
+
+	var proxy string
+	for key, path := range map[string]*string{"ca_file": &t.caFile, "cert_file": &t.certFile, "key_file": &t.keyFile, "proxy": &proxy} {
+		v, ok := settings[key]
+		if !ok {
+			continue
+		}
+
+		s, ok := v.(string)
+		if !ok {
+			return t, errors.Errorf("taskfile: http: %s must be a string", key)
+		}
+
+		var err error
+		*path, err = ctx.ExpandTilde(ctx.ExpandAll(s))
+		if err != nil {
+			return t, invalid(key, errors.Wrapf(err, "taskfile: http: could not expand %s", key))
+		}
+	}
+
+	if (t.certFile == "") != (t.keyFile == "") {
+		return t, errors.New("taskfile: http: cert_file and key_file must be defined together")
+	}
+
+	if proxy != "" {
+		if _, err := url.Parse(proxy); err != nil {
+			return t, invalid("proxy", errors.Wrap(err, "taskfile: http: proxy"))
+		}
+		t.proxy = proxy
+	}
+
+	for key, b := range map[string]*bool{"insecure_skip_verify": &t.insecure, "http2": &t.http2} {
+		v, ok := settings[key]
+		if !ok {
+			continue
+		}
+
+		*b, ok = v.(bool)
+		if !ok {
+			return t, errors.Errorf("taskfile: http: %s must be a boolean", key)
+		}
+	}
+
+	if v, ok := settings["max_redirects"]; ok {
+		t.maxRedirects, ok = v.(int)
+		if !ok || t.maxRedirects < 0 {
+			return t, errors.New("taskfile: http: max_redirects must be a positive integer or zero")
+		}
+	}
+
+	return t, nil
+}
+
+// client returns the client of the transport, shared by the commands with the same transport.
+// The client is created again at the next call when its creation fails (e.g. a missing certificate).
+func (t transport) client() (*nethttp.Client, error) {
+	clients.Lock()
+	defer clients.Unlock()
+
+	stamp := t.stamp()
+	cached, ok := clients.m[t]
+	if ok && cached.stamp == stamp {
+		return cached.client, nil
+	}
+
+	client, err := t.newClient()
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		cached.client.CloseIdleConnections() // The connections in use are closed once their requests are done.
+	}
+
+	clients.m[t] = &cachedClient{
+		client: client,
+		stamp:  stamp,
+	}
+	return client, nil
+}
+
+// stamp returns the version of the certificate files of the transport, according to their size and modification time.
+func (t transport) stamp() string {
+	var stamp strings.Builder
+	for _, path := range []string{t.caFile, t.certFile, t.keyFile} {
+		if path == "" {
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			stamp.WriteString("-;") // The client fails to read it.
+			continue
+		}
+		fmt.Fprintf(&stamp, "%d:%d;", info.Size(), info.ModTime().UnixNano())
+	}
+	return stamp.String()
+}
+
+// newClient returns a new client of the transport, reading its certificates.
+func (t transport) newClient() (*nethttp.Client, error) {
+	tr := nethttp.DefaultTransport.(*nethttp.Transport).Clone()
+	tr.DialContext = (&net.Dialer{
+		Timeout:   t.connectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	tr.TLSClientConfig = &tls.Config{
+		InsecureSkipVerify: t.insecure,
+	}
+
+	if t.caFile != "" {
+		pem, err := os.ReadFile(t.caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "taskfile: http: ca_file")
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("taskfile: http: ca_file: no certificate found in %s", t.caFile)
+		}
+		tr.TLSClientConfig.RootCAs = pool
+	}
+
+	if t.certFile != "" {
+		certificate, err := tls.LoadX509KeyPair(t.certFile, t.keyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "taskfile: http: cert_file")
+		}
+		tr.TLSClientConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	if t.proxy != "" {
+		proxy, err := url.Parse(t.proxy)
+		if err != nil {
+			return nil, errors.Wrap(err, "taskfile: http: proxy")
+		}
+		tr.Proxy = nethttp.ProxyURL(proxy)
+	}
+
+	if !t.http2 {
+		tr.ForceAttemptHTTP2 = false
+		tr.TLSNextProto = map[string]func(string, *tls.Conn) nethttp.RoundTripper{} // Disables HTTP/2.
+	}
+
+	return &nethttp.Client{
+		Transport: tr,
+		Timeout:   t.timeout,
+		CheckRedirect: func(_ *nethttp.Request, via []*nethttp.Request) error {
+			if t.maxRedirects == 0 {
+				return nethttp.ErrUseLastResponse // The redirect response is the response of the request.
+			}
+			if len(via) >= t.maxRedirects {
+				return errors.Errorf("stopped after %d redirects", t.maxRedirects)
+			}
+			return nil
+		},
+	}, nil
+}
@@ -0,0 +1,48 @@
+package runner
+
+import (
+	"encoding/pem"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTransportClient(t *testing.T) {
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := func(tr transport) any {
+		t.Helper()
+
+		c, err := tr.client()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	first := client(transport{caFile: caFile, maxRedirects: DefaultMaxRedirects})
+	if client(transport{caFile: caFile, maxRedirects: DefaultMaxRedirects}) != first {
+		t.Error("the client is not shared by the same transports")
+	}
+	if client(transport{caFile: caFile, maxRedirects: 1}) == first {
+		t.Error("the client is shared by different transports")
+	}
+
+	// The renewed certificate is read by a new client.
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(caFile, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if client(transport{caFile: caFile, maxRedirects: DefaultMaxRedirects}) == first {
+		t.Error("the client is not created again when its certificate changes")
+	}
+}
@@ -1,11 +1,15 @@
 package runner
 
 import (
+	"context"
 	"os"
 	"reflect"
 	"strings"
 	"time"
 
+	"github.com/mdouchement/logger"
+	"github.com/mdouchement/shigoto/pkg/io"
+	"github.com/mdouchement/shigoto/pkg/templater"
 	"github.com/pkg/errors"
 	"github.com/traefik/yaegi/interp"
 	"github.com/traefik/yaegi/stdlib"
@@ -18,63 +22,64 @@ type yaegi struct {
 	src string
 }
 
-func (r *yaegi) Run() {
-	r.Lock()
-	defer r.Unlock()
-
-	// Reset previous state
-	r.err = nil
-
-	//
-
-	start := time.Now()
-	logger := r.log.WithPrefixf("[%s]", r.ctx.Name()).WithField("id", GenerateID())
+func (r *yaegi) Run(ctx context.Context) Result {
+	result := r.result()
+	log := logger.LogWith(ctx) // Exposed to the script.
+	logger := r.logger(ctx)
 	logger.Info("Running Yaegi script")
 	if r.ctx.LogsFile() != nil {
 		defer r.ctx.LogsFile().Sync()
 	}
 
-	i := interp.New(interp.Options{})
+	stdout, stderr := io.NewCounter(captured(ctx, os.Stdout)), io.NewCounter(os.Stderr)
+	i := interp.New(interp.Options{
+		Stdout: stdout,
+		Stderr: stderr,
+		Env:    r.environ(ctx),
+	})
 	// i.Use(syscall.Symbols)
 	// i.Use(unsafe.Symbols)
 	i.Use(stdlib.Symbols)
 	i.Use(unrestricted.Symbols)
 	i.Use(interp.Exports{
 		"logger/logger": { // Use as `import "logger"`
-			"WithPrefix":  reflect.ValueOf(r.log.WithPrefix),
-			"WithPrefixf": reflect.ValueOf(r.log.WithPrefixf),
-			"WithField":   reflect.ValueOf(r.log.WithField),
-			"WithError":   reflect.ValueOf(r.log.WithError),
-			"WithFields":  reflect.ValueOf(r.log.WithFields),
+			"WithPrefix":  reflect.ValueOf(log.WithPrefix),
+			"WithPrefixf": reflect.ValueOf(log.WithPrefixf),
+			"WithField":   reflect.ValueOf(log.WithField),
+			"WithError":   reflect.ValueOf(log.WithError),
+			"WithFields":  reflect.ValueOf(log.WithFields),
 			//
-			"Debug":  reflect.ValueOf(r.log.Debug),
-			"Debugf": reflect.ValueOf(r.log.Debugf),
-			"Info":   reflect.ValueOf(r.log.Info),
-			"Infof":  reflect.ValueOf(r.log.Infof),
-			"Warn":   reflect.ValueOf(r.log.Warn),
-			"Warnf":  reflect.ValueOf(r.log.Warnf),
-			"Error":  reflect.ValueOf(r.log.Error),
-			"Errorf": reflect.ValueOf(r.log.Errorf),
+			"Debug":  reflect.ValueOf(log.Debug),
+			"Debugf": reflect.ValueOf(log.Debugf),
+			"Info":   reflect.ValueOf(log.Info),
+			"Infof":  reflect.ValueOf(log.Infof),
+			"Warn":   reflect.ValueOf(log.Warn),
+			"Warnf":  reflect.ValueOf(log.Warnf),
+			"Error":  reflect.ValueOf(log.Error),
+			"Errorf": reflect.ValueOf(log.Errorf),
 			//
-			"Print":   reflect.ValueOf(r.log.Print),
-			"Printf":  reflect.ValueOf(r.log.Printf),
-			"Println": reflect.ValueOf(r.log.Println),
-			"Fatal":   reflect.ValueOf(r.log.Fatal),
-			"Fatalf":  reflect.ValueOf(r.log.Fatalf),
-			"Fatalln": reflect.ValueOf(r.log.Fatalln),
-			"Panic":   reflect.ValueOf(r.log.Panic),
-			"Panicf":  reflect.ValueOf(r.log.Panicf),
-			"Panicln": reflect.ValueOf(r.log.Panicln),
+			"Print":   reflect.ValueOf(log.Print),
+			"Printf":  reflect.ValueOf(log.Printf),
+			"Println": reflect.ValueOf(log.Println),
+			"Fatal":   reflect.ValueOf(log.Fatal),
+			"Fatalf":  reflect.ValueOf(log.Fatalf),
+			"Fatalln": reflect.ValueOf(log.Fatalln),
+			"Panic":   reflect.ValueOf(log.Panic),
+			"Panicf":  reflect.ValueOf(log.Panicf),
+			"Panicln": reflect.ValueOf(log.Panicln),
 		},
 	})
 
-	if _, err := i.Eval(r.src); err != nil {
-		r.err = err
-		logger.WithField("elapsed_time", time.Since(start)).WithField("ignored", r.ignoreError).Error(err)
-		return
+	// The evaluation is interrupted when the context is done.
+	_, err := i.EvalWithContext(ctx, r.src)
+	result.Output = stdout.Count() + stderr.Count()
+	if err != nil {
+		logger.WithField("elapsed_time", time.Since(result.StartTime)).WithField("ignored", r.ignoreError).Error(err)
+		return result.end(err)
 	}
 
-	logger.WithField("elapsed_time", time.Since(start)).Info("finished")
+	logger.WithField("elapsed_time", time.Since(result.StartTime)).Info("finished")
+	return result.end(nil)
 }
 
 func init() {
@@ -94,21 +99,30 @@ func init() {
 		if !ok {
 			return nil, errors.New("taskfile: yaegi: src must be a string")
 		}
+		executor.label = "yaegi script"
 
 		// Check if src is a file and not plain code.
 		if strings.HasSuffix(strings.TrimSpace(executor.src), ".go") {
 			executor.src = executor.ctx.ExpandAll(executor.src)
 			var err error
 			executor.src, err = executor.ctx.ExpandTilde(executor.src)
-			if !ok {
-				return nil, errors.Wrap(err, "taskfile: yaegi: expand filename")
+			if err != nil {
+				return nil, invalid("yaegi", errors.Wrap(err, "taskfile: yaegi: expand filename"))
 			}
 
+			executor.label = executor.src
+
 			src, err := os.ReadFile(executor.src)
 			if err != nil {
+				return nil, invalid("yaegi", errors.Wrap(err, "taskfile: yaegi: file"))
+			}
+
+			// The content of the file is not part of the payload so it is rendered here.
+			templater := templater.New(ctx)
+			executor.src = templater.Replace(string(src))
+			if err = templater.Err(); err != nil {
 				return nil, errors.Wrap(err, "taskfile: yaegi: file")
 			}
-			executor.src = string(src)
 		}
 
 		executor.src = executor.ctx.ExpandAll(executor.src)
@@ -3,6 +3,8 @@ package shigoto
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/knadh/koanf"
 	"github.com/mdouchement/shigoto/pkg/io"
@@ -16,24 +18,60 @@ import (
 type (
 	// A Baito (aka Arubaito from German Arbeit) is a task/job that will be runned at a scheduled time.
 	Baito struct {
-		FieldName        string
-		FieldSchedule    Schedule
-		FieldWorkdir     string
-		FieldLogsFile    io.WriteSyncer
-		FieldVariables   map[string]string
-		FieldEnvironment map[string]string
-		FieldCommands    []runner.Runner
+		FieldName             string
+		FieldSchedule         Schedule
+		FieldAfter            []Dependency
+		FieldCatchUp          CatchUp
+		FieldConcurrency      Concurrency
+		FieldLocks            []string
+		FieldStartingDeadline time.Duration
+		FieldTimeout          time.Duration
+		FieldRetry            *runner.RetryPolicy
+		FieldWorkdir          string
+		FieldLogsFile         io.WriteSyncer
+		FieldVariables        map[string]string
+		FieldEnvironment      map[string]string
+		FieldHTTP             map[string]any
+		FieldCommands         []runner.Runner
 	}
 
 	// A Schedule describes a job's duty cycle.
 	Schedule cron.Schedule
 
+	// A CatchUp is the policy applied to the runs missed while the daemon was down.
+	CatchUp string
+
+	// A Concurrency is the policy applied when a run starts while the previous one is still running.
+	Concurrency string
+
 	schedule struct {
 		Schedule
 		raw string
 	}
 )
 
+// Catch-up policies.
+const (
+	// CatchUpNone does not run the missed runs.
+	CatchUpNone CatchUp = "none"
+	// CatchUpOnce runs once the baito if at least one run has been missed.
+	CatchUpOnce CatchUp = "once"
+	// CatchUpAll runs every missed run.
+	CatchUpAll CatchUp = "all"
+)
+
+// Concurrency policies.
+const (
+	// ConcurrencySkip skips the new run.
+	ConcurrencySkip Concurrency = "skip"
+	// ConcurrencyQueue delays the new run until the end of the previous one.
+	ConcurrencyQueue Concurrency = "queue"
+	// ConcurrencyAllow runs in parallel the new run.
+	ConcurrencyAllow Concurrency = "allow"
+	// ConcurrencyReplace interrupts the previous run and starts the new one.
+	ConcurrencyReplace Concurrency = "replace"
+)
+
 func (s *schedule) String() string {
 	return s.raw
 }
@@ -44,10 +82,44 @@ func (b *Baito) Name() string {
 }
 
 // Schedule returns the schedule.
+// It is nil for a baito only triggered by its dependencies.
 func (b *Baito) Schedule() Schedule {
 	return b.FieldSchedule
 }
 
+// CatchUp returns the policy applied to the runs missed while the daemon was down.
+func (b *Baito) CatchUp() CatchUp {
+	return b.FieldCatchUp
+}
+
+// StartingDeadline returns the maximum delay after which a missed run is dropped.
+// A zero value means no deadline.
+func (b *Baito) StartingDeadline() time.Duration {
+	return b.FieldStartingDeadline
+}
+
+// Concurrency returns the policy applied when a run starts while the previous one is still running.
+func (b *Baito) Concurrency() Concurrency {
+	return b.FieldConcurrency
+}
+
+// Locks returns the names of the locks held while the baito is running.
+func (b *Baito) Locks() []string {
+	return b.FieldLocks
+}
+
+// Timeout returns the maximum duration of a run.
+// A zero value means no timeout.
+func (b *Baito) Timeout() time.Duration {
+	return b.FieldTimeout
+}
+
+// Retry returns the policy applied when a run fails.
+// A nil value means no retry.
+func (b *Baito) Retry() *runner.RetryPolicy {
+	return b.FieldRetry
+}
+
 // Workdir returns the working directory.
 func (b *Baito) Workdir() string {
 	return b.FieldWorkdir
@@ -68,6 +140,11 @@ func (b *Baito) Environment() map[string]string {
 	return b.FieldEnvironment
 }
 
+// HTTP returns the file-level settings of the HTTP requests.
+func (b *Baito) HTTP() map[string]any {
+	return b.FieldHTTP
+}
+
 // Commands returns the commands to be executed.
 func (b *Baito) Commands() []runner.Runner {
 	return b.FieldCommands
@@ -108,14 +185,40 @@ func loadBaito(konf *koanf.Koanf, name string) (*Baito, error) {
 	baito.loadEnvironment(konf)
 	baito.loadWorkdir(konf)
 
+	if err := baito.loadAfter(konf); err != nil {
+		return nil, err
+	}
+
 	if err := baito.loadSchedule(konf); err != nil {
 		return nil, err
 	}
 
+	if err := baito.loadCatchUp(konf); err != nil {
+		return nil, err
+	}
+
+	if err := baito.loadConcurrency(konf); err != nil {
+		return nil, err
+	}
+
+	baito.loadLocks(konf)
+
+	if err := baito.loadTimeout(konf); err != nil {
+		return nil, err
+	}
+
+	if err := baito.loadRetry(konf); err != nil {
+		return nil, err
+	}
+
 	if err := baito.loadLogsFile(konf); err != nil {
 		return nil, err
 	}
 
+	if err := baito.loadHTTP(konf); err != nil {
+		return nil, err
+	}
+
 	if err := baito.loadCommands(konf); err != nil {
 		return nil, err
 	}
@@ -159,6 +262,9 @@ func (b *Baito) loadWorkdir(konf *koanf.Koanf) (err error) {
 func (b *Baito) loadSchedule(konf *koanf.Koanf) error {
 	path := fmt.Sprintf("%s.%s.schedule", entrypoint, b.FieldName)
 	if !konf.Exists(path) {
+		if len(b.FieldAfter) > 0 {
+			return nil // Only triggered by its dependencies.
+		}
 		return errors.Errorf("%s: missing schedule", path)
 	}
 
@@ -167,8 +273,82 @@ func (b *Baito) loadSchedule(konf *koanf.Koanf) error {
 	}
 	b.FieldSchedule = s
 
+	timezone := fmt.Sprintf("%s.%s.timezone", entrypoint, b.FieldName)
+	if tz := konf.String(timezone); tz != "" {
+		if strings.HasPrefix(s.raw, "TZ=") || strings.HasPrefix(s.raw, "CRON_TZ=") {
+			return errors.Errorf("%s: timezone already defined by the schedule", timezone)
+		}
+
+		if _, err := time.LoadLocation(tz); err != nil {
+			return errors.Wrap(err, timezone)
+		}
+
+		s.raw = fmt.Sprintf("CRON_TZ=%s %s", tz, s.raw)
+	}
+
 	var err error
-	s.Schedule, err = cron.ParseStandard(s.raw)
+	s.Schedule, err = parseSchedule(s.raw)
+	return errors.Wrap(err, path)
+}
+
+func (b *Baito) loadCatchUp(konf *koanf.Koanf) (err error) {
+	path := fmt.Sprintf("%s.%s.catch_up", entrypoint, b.FieldName)
+
+	b.FieldCatchUp = CatchUp(konf.String(path))
+	switch b.FieldCatchUp {
+	case "":
+		b.FieldCatchUp = CatchUpNone
+	case CatchUpNone, CatchUpOnce, CatchUpAll:
+	default:
+		return errors.Errorf("%s: unsupported policy '%s'", path, b.FieldCatchUp)
+	}
+
+	path = fmt.Sprintf("%s.%s.starting_deadline", entrypoint, b.FieldName)
+	if !konf.Exists(path) {
+		return nil
+	}
+
+	b.FieldStartingDeadline, err = time.ParseDuration(konf.String(path))
+	return errors.Wrap(err, path)
+}
+
+func (b *Baito) loadConcurrency(konf *koanf.Koanf) error {
+	path := fmt.Sprintf("%s.%s.concurrency", entrypoint, b.FieldName)
+
+	b.FieldConcurrency = Concurrency(konf.String(path))
+	switch b.FieldConcurrency {
+	case "":
+		b.FieldConcurrency = ConcurrencySkip
+	case ConcurrencySkip, ConcurrencyQueue, ConcurrencyAllow, ConcurrencyReplace:
+	default:
+		return errors.Errorf("%s: unsupported policy '%s'", path, b.FieldConcurrency)
+	}
+
+	return nil
+}
+
+func (b *Baito) loadLocks(konf *koanf.Koanf) {
+	path := fmt.Sprintf("%s.%s.locks", entrypoint, b.FieldName)
+	b.FieldLocks = konf.Strings(path)
+}
+
+func (b *Baito) loadTimeout(konf *koanf.Koanf) (err error) {
+	path := fmt.Sprintf("%s.%s.timeout", entrypoint, b.FieldName)
+	if !konf.Exists(path) {
+		return nil
+	}
+
+	b.FieldTimeout, err = time.ParseDuration(konf.String(path))
+	return errors.Wrap(err, path)
+}
+
+func (b *Baito) loadRetry(konf *koanf.Koanf) (err error) {
+	path := fmt.Sprintf("%s.%s.retry", entrypoint, b.FieldName)
+	if !konf.Exists(path) {
+		return nil
+	}
+
+	b.FieldRetry, err = runner.ParseRetry(konf.Get(path))
 	return errors.Wrap(err, path)
 }
 
@@ -189,6 +369,20 @@ func (b *Baito) loadLogsFile(konf *koanf.Koanf) (err error) {
 	return errors.Wrap(err, "could not create logs redirection file")
 }
 
+func (b *Baito) loadHTTP(konf *koanf.Koanf) error {
+	if !konf.Exists(globalhttp) {
+		return nil
+	}
+
+	var ok bool
+	b.FieldHTTP, ok = konf.Get(globalhttp).(map[string]any)
+	if !ok {
+		return errors.Errorf("%s: must be a map", globalhttp)
+	}
+
+	return nil
+}
+
 func (b *Baito) loadCommands(konf *koanf.Koanf) error {
 	path := fmt.Sprintf("%s.%s.commands", entrypoint, b.FieldName)
 	if !konf.Exists(path) {
@@ -200,17 +394,14 @@ func (b *Baito) loadCommands(konf *koanf.Koanf) error {
 		return errors.Errorf("%s: expected commands to be an array", path)
 	}
 
-	templater := templater.New(b)
 	for i, command := range sl {
 		var err error
 		var c runner.Runner
 
 		switch v := command.(type) {
 		case string:
-			v = templater.Replace(v)
 			c, err = runner.Lookup(b, map[string]any{"exec": v})
 		case map[string]any:
-			v = templater.ReplaceMapI(v)
 			c, err = runner.Lookup(b, v)
 		default:
 			return errors.Errorf("%s: invalid command format", path)
@@ -222,5 +413,5 @@ func (b *Baito) loadCommands(konf *koanf.Koanf) error {
 		b.FieldCommands = append(b.FieldCommands, c)
 	}
 
-	return templater.Err()
+	return nil
 }
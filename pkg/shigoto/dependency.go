@@ -0,0 +1,226 @@
+package shigoto
+
+import (
+	"cmp"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/knadh/koanf"
+	"github.com/pkg/errors"
+)
+
+type (
+	// A Dependency is a baito whose end triggers the baito depending on it.
+	Dependency struct {
+		Shigoto string
+		Baito   string
+		On      Condition
+	}
+
+	// A Condition is the outcome of a baito run that triggers its dependents.
+	Condition string
+
+	// A Graph is the dependency graph of a set of shigoto.
+	Graph struct {
+		shigoto []*Shigoto
+	}
+)
+
+// Dependency conditions.
+const (
+	// ConditionSuccess triggers the dependent when the run succeeded.
+	ConditionSuccess Condition = "success"
+	// ConditionFailure triggers the dependent when the run failed.
+	ConditionFailure Condition = "failure"
+	// ConditionCompletion triggers the dependent whatever the outcome of the run.
+	ConditionCompletion Condition = "completion"
+)
+
+// Matches returns true if a run with the given outcome satisfies the condition.
+func (c Condition) Matches(succeeded bool) bool {
+	switch c {
+	case ConditionSuccess:
+		return succeeded
+	case ConditionFailure:
+		return !succeeded
+	}
+	return true
+}
+
+// Is returns true if the dependency targets the given baito.
+func (d Dependency) Is(shigoto, baito string) bool {
+	return d.Shigoto == shigoto && d.Baito == baito
+}
+
+func (d Dependency) String() string {
+	return fmt.Sprintf("%s (%s)", path.Join(d.Shigoto, d.Baito), d.On)
+}
+
+// After returns the baito triggering this baito when they end.
+func (b *Baito) After() []Dependency {
+	return b.FieldAfter
+}
+
+// loadAfter loads the dependencies of the baito, `depends_on` being an alias of `after`.
+// A dependency is either the name of a baito of the same shigoto or a map with the keys shigoto, baito and on.
+// The shigoto of the dependencies targeting the same file is set by Load.
+func (b *Baito) loadAfter(konf *koanf.Koanf) error {
+	path := fmt.Sprintf("%s.%s.after", entrypoint, b.FieldName)
+	if alias := fmt.Sprintf("%s.%s.depends_on", entrypoint, b.FieldName); konf.Exists(alias) {
+		if konf.Exists(path) {
+			return errors.Errorf("%s: both after and depends_on are defined", alias)
+		}
+		path = alias
+	}
+
+	if !konf.Exists(path) {
+		return nil
+	}
+
+	sl, ok := konf.Get(path).([]any)
+	if !ok {
+		return errors.Errorf("%s: expected dependencies to be an array", path)
+	}
+
+	for i, v := range sl {
+		dependency := Dependency{
+			On: ConditionSuccess,
+		}
+
+		switch v := v.(type) {
+		case string:
+			dependency.Baito = v
+		case map[string]any:
+			dependency.Shigoto, _ = v["shigoto"].(string)
+			dependency.Baito, _ = v["baito"].(string)
+			if on, ok := v["on"].(string); ok {
+				dependency.On = Condition(on)
+			}
+		default:
+			return errors.Errorf("%s[%d]: invalid dependency format", path, i)
+		}
+
+		if dependency.Baito == "" {
+			return errors.Errorf("%s[%d]: missing baito", path, i)
+		}
+
+		switch dependency.On {
+		case ConditionSuccess, ConditionFailure, ConditionCompletion:
+		default:
+			return errors.Errorf("%s[%d]: unsupported condition '%s'", path, i, dependency.On)
+		}
+
+		b.FieldAfter = append(b.FieldAfter, dependency)
+	}
+
+	return nil
+}
+
+// NewGraph returns the dependency graph of the given shigoto.
+func NewGraph(shigoto ...*Shigoto) *Graph {
+	g := &Graph{
+		shigoto: slices.Clone(shigoto),
+	}
+
+	slices.SortFunc(g.shigoto, func(a, b *Shigoto) int {
+		return cmp.Compare(a.Name, b.Name)
+	})
+	return g
+}
+
+// Validate returns an error if a dependency targets an unknown baito or if the graph contains a cycle.
+func (g *Graph) Validate() error {
+	const (
+		visiting = iota + 1
+		visited
+	)
+
+	state := map[[2]string]int{}
+
+	var visit func(node [2]string, stack []string) error
+	visit = func(node [2]string, stack []string) error {
+		stack = append(stack, path.Join(node[0], node[1]))
+
+		switch state[node] {
+		case visiting:
+			return errors.Errorf("dependency cycle: %s", strings.Join(stack, " -> "))
+		case visited:
+			return nil
+		}
+
+		state[node] = visiting
+		for _, dependency := range g.baito(node[0], node[1]).After() {
+			if err := visit([2]string{dependency.Shigoto, dependency.Baito}, stack); err != nil {
+				return err
+			}
+		}
+		state[node] = visited
+		return nil
+	}
+
+	for _, s := range g.shigoto {
+		for _, name := range s.names() {
+			baito := s.Baito[name]
+
+			for _, dependency := range baito.After() {
+				if g.baito(dependency.Shigoto, dependency.Baito) == nil {
+					return errors.Errorf("%s: unknown dependency %s", path.Join(s.Name, name), path.Join(dependency.Shigoto, dependency.Baito))
+				}
+			}
+		}
+	}
+
+	for _, s := range g.shigoto {
+		for _, name := range s.names() {
+			if err := visit([2]string{s.Name, name}, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// DOT returns the graph in the Graphviz DOT language.
+// The edges go from a baito to the baito it triggers.
+func (g *Graph) DOT() string {
+	var b strings.Builder
+
+	b.WriteString("digraph shigoto {\n")
+	for _, s := range g.shigoto {
+		fmt.Fprintf(&b, "\tsubgraph %q {\n", "cluster_"+s.Name)
+		fmt.Fprintf(&b, "\t\tlabel = %q;\n", s.Name)
+		for _, name := range s.names() {
+			fmt.Fprintf(&b, "\t\t%q [label=%q];\n", path.Join(s.Name, name), name)
+		}
+		b.WriteString("\t}\n")
+	}
+
+	for _, s := range g.shigoto {
+		for _, name := range s.names() {
+			baito := s.Baito[name]
+
+			for _, dependency := range baito.After() {
+				fmt.Fprintf(&b, "\t%q -> %q [label=%q];\n", path.Join(dependency.Shigoto, dependency.Baito), path.Join(s.Name, name), dependency.On)
+			}
+		}
+	}
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+func (g *Graph) baito(shigoto, name string) *Baito {
+	for _, s := range g.shigoto {
+		if s.Name != shigoto {
+			continue
+		}
+
+		if baito, ok := s.Baito[name]; ok {
+			return &baito
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package shigoto
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGraphValidate(t *testing.T) {
+	after := func(shigoto, baito string) Dependency {
+		return Dependency{Shigoto: shigoto, Baito: baito, On: ConditionSuccess}
+	}
+
+	// newShigoto returns a shigoto whose baito depend on the given dependencies.
+	newShigoto := func(name string, baito map[string][]Dependency) *Shigoto {
+		s := &Shigoto{
+			Name:  name,
+			Baito: map[string]Baito{},
+		}
+		for baitoName, dependencies := range baito {
+			s.Baito[baitoName] = Baito{FieldName: baitoName, FieldAfter: dependencies}
+		}
+		return s
+	}
+
+	tests := []struct {
+		name    string
+		shigoto []*Shigoto
+		err     string
+	}{
+		{
+			name: "valid graph",
+			shigoto: []*Shigoto{
+				newShigoto("a.yml", map[string][]Dependency{
+					"backup": nil,
+					"upload": {after("a.yml", "backup")},
+				}),
+				newShigoto("b.yml", map[string][]Dependency{
+					"report": {after("a.yml", "upload"), after("a.yml", "backup")},
+				}),
+			},
+		},
+		{
+			name: "self cycle",
+			shigoto: []*Shigoto{
+				newShigoto("a.yml", map[string][]Dependency{
+					"backup": {after("a.yml", "backup")},
+				}),
+			},
+			err: "dependency cycle: a.yml/backup -> a.yml/backup",
+		},
+		{
+			name: "indirect cycle",
+			shigoto: []*Shigoto{
+				newShigoto("a.yml", map[string][]Dependency{
+					"backup": {after("b.yml", "report")},
+					"upload": {after("a.yml", "backup")},
+				}),
+				newShigoto("b.yml", map[string][]Dependency{
+					"report": {after("a.yml", "upload")},
+				}),
+			},
+			err: "dependency cycle: a.yml/backup -> b.yml/report -> a.yml/upload -> a.yml/backup",
+		},
+		{
+			name: "missing baito",
+			shigoto: []*Shigoto{
+				newShigoto("a.yml", map[string][]Dependency{
+					"upload": {after("a.yml", "backup")},
+				}),
+			},
+			err: "a.yml/upload: unknown dependency a.yml/backup",
+		},
+		{
+			name: "missing shigoto",
+			shigoto: []*Shigoto{
+				newShigoto("a.yml", map[string][]Dependency{
+					"upload": {after("b.yml", "upload")},
+				}),
+			},
+			err: "a.yml/upload: unknown dependency b.yml/upload",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewGraph(tt.shigoto...).Validate()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.err != "" && err == nil:
+				t.Fatalf("expected error %q", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Fatalf("got error %q, expected %q", err, tt.err)
+			}
+		})
+	}
+}
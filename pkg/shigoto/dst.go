@@ -0,0 +1,98 @@
+package shigoto
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// starBit is the bit set by the cron parser on wildcard fields.
+const starBit = 1 << 63
+
+// A dst wraps a cron schedule to apply the following policy at daylight saving time transitions
+// for the schedules with fixed hours (e.g. `30 2 * * *`):
+//   - fire times skipped by a forward transition are run once right after the transition,
+//   - fire times repeated by a backward transition are run only on their first occurrence.
+//
+// Schedules with a wildcard hour (e.g. `*/15 * * * *`) follow the elapsed time and are not wrapped.
+type dst struct {
+	*cron.SpecSchedule
+}
+
+func withDST(s cron.Schedule) cron.Schedule {
+	spec, ok := s.(*cron.SpecSchedule)
+	if !ok || spec.Hour&starBit != 0 {
+		return s
+	}
+
+	return &dst{SpecSchedule: spec}
+}
+
+func (s *dst) Next(t time.Time) time.Time {
+	next := s.SpecSchedule.Next(t)
+	if next.IsZero() {
+		return next
+	}
+
+	loc := s.Location
+	if loc == time.Local {
+		loc = t.Location() // Same behavior as cron.SpecSchedule
+	}
+
+	if at, ok := s.skipped(t.In(loc), next.In(loc)); ok {
+		return at.In(t.Location())
+	}
+
+	if repeated(next.In(loc)) {
+		return s.Next(next)
+	}
+
+	return next
+}
+
+// skipped returns the forward transition between from and to if it hides a fire time.
+func (s *dst) skipped(from, to time.Time) (time.Time, bool) {
+	_, before := from.Zone()
+	_, after := to.Zone()
+	if after <= before {
+		return time.Time{}, false
+	}
+
+	// Look for the transition instant.
+	lo, hi := from, to
+	for hi.Sub(lo) > time.Nanosecond {
+		mid := lo.Add(hi.Sub(lo) / 2)
+		if _, offset := mid.Zone(); offset == before {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	// The wall clock range skipped by the transition, evaluated without location.
+	start := wall(hi.In(time.FixedZone("", before)))
+	end := start.Add(time.Duration(after-before) * time.Second)
+
+	naive := *s.SpecSchedule
+	naive.Location = time.UTC
+
+	fire := naive.Next(start.Add(-time.Second))
+	return hi, !fire.IsZero() && fire.Before(end)
+}
+
+// repeated returns true if the wall clock of t has already been seen before a backward transition.
+func repeated(t time.Time) bool {
+	_, offset := t.Zone()
+	_, previous := t.Add(-24 * time.Hour).Zone()
+	if previous <= offset {
+		return false
+	}
+
+	_, first := t.Add(-time.Duration(previous-offset) * time.Second).Zone()
+	return first == previous
+}
+
+// wall returns the wall clock of t as an UTC time.
+func wall(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
@@ -0,0 +1,95 @@
+package shigoto
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleDST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("Europe/Paris time zone not available:", err)
+	}
+
+	at := func(month time.Month, day, hour, min int, offset int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, time.FixedZone("", offset*3600))
+	}
+
+	tests := []struct {
+		name  string
+		spec  string
+		from  time.Time
+		fires []time.Time
+	}{
+		{
+			name: "spring forward runs the skipped time at the transition",
+			spec: "30 2 * * *",
+			from: at(time.March, 28, 3, 0, 1),
+			fires: []time.Time{
+				at(time.March, 29, 3, 0, 2), // 02:30 does not exist
+				at(time.March, 30, 2, 30, 2),
+			},
+		},
+		{
+			name: "spring forward does not move the times outside of the gap",
+			spec: "30 1 * * *",
+			from: at(time.March, 28, 3, 0, 1),
+			fires: []time.Time{
+				at(time.March, 29, 1, 30, 1),
+				at(time.March, 30, 1, 30, 2),
+			},
+		},
+		{
+			name: "fall back runs the repeated time once",
+			spec: "30 2 * * *",
+			from: at(time.October, 24, 3, 0, 2),
+			fires: []time.Time{
+				at(time.October, 25, 2, 30, 2), // First 02:30, the second one is skipped
+				at(time.October, 26, 2, 30, 1),
+			},
+		},
+		{
+			name: "wildcard hours follow the elapsed time",
+			spec: "0 * * * *",
+			from: at(time.October, 25, 1, 30, 2),
+			fires: []time.Time{
+				at(time.October, 25, 2, 0, 2),
+				at(time.October, 25, 2, 0, 1), // Repeated hour
+				at(time.October, 25, 3, 0, 1),
+			},
+		},
+		{
+			name: "day operators follow the transitions",
+			spec: "30 2 L * *",
+			from: at(time.October, 1, 0, 0, 2),
+			fires: []time.Time{
+				at(time.October, 31, 2, 30, 1),
+			},
+		},
+		{
+			name: "day operators run the skipped time at the transition",
+			spec: "30 2 * * 0L",
+			from: at(time.March, 1, 0, 0, 1),
+			fires: []time.Time{
+				at(time.March, 29, 3, 0, 2), // Last Sunday of March, 02:30 does not exist
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseSchedule("CRON_TZ=Europe/Paris " + tt.spec)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.spec, err)
+			}
+
+			next := tt.from.In(paris)
+			for i, expected := range tt.fires {
+				next = schedule.Next(next)
+				if !next.Equal(expected) {
+					t.Fatalf("fire #%d: got %s, expected %s", i, next, expected.In(paris))
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,10 @@
 package shigoto
 
 import (
+	"maps"
 	"path/filepath"
 	"reflect"
+	"slices"
 
 	"github.com/knadh/koanf"
 	"github.com/knadh/koanf/parsers/yaml"
@@ -12,6 +14,7 @@ import (
 const (
 	globalvariables = "variables"
 	globalenv       = "environment"
+	globalhttp      = "http"
 	entrypoint      = "shigoto"
 )
 
@@ -41,6 +44,12 @@ func Load(filename string) (*Shigoto, error) {
 			return nil, err
 		}
 
+		for i, dependency := range b.FieldAfter {
+			if dependency.Shigoto == "" {
+				b.FieldAfter[i].Shigoto = shigoto.Name
+			}
+		}
+
 		shigoto.Baito[name] = *b
 	}
 
@@ -51,3 +60,8 @@ func Load(filename string) (*Shigoto, error) {
 func (s *Shigoto) Same(shigoto *Shigoto) bool {
 	return reflect.DeepEqual(s.konf, shigoto.konf)
 }
+
+// names returns the sorted names of the baito.
+func (s *Shigoto) names() []string {
+	return slices.Sorted(maps.Keys(s.Baito))
+}
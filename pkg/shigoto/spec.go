@@ -0,0 +1,295 @@
+package shigoto
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+)
+
+// parser accepts the standard crontab expressions with an optional leading seconds field.
+var parser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+var weekdays = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+type (
+	// An extended schedule supports the following day operators on top of the crontab expressions:
+	//   - day of month `L`: the last day of the month, `L-3` for the third to last day
+	//   - day of month `15W`: the nearest weekday of the 15th, `LW` for the last weekday of the month
+	//   - day of week `5L`: the last Friday of the month
+	//   - day of week `3#2`: the second Wednesday of the month
+	extended struct {
+		time     cron.Schedule
+		location *time.Location
+		dom      days
+		dow      days
+	}
+
+	// days matches the days of a month.
+	days struct {
+		restricted bool
+		bits       uint64
+		operators  []func(time.Time) bool
+	}
+)
+
+// parseSchedule parses the given schedule expression.
+func parseSchedule(spec string) (cron.Schedule, error) {
+	var prefix string
+	if strings.HasPrefix(spec, "TZ=") || strings.HasPrefix(spec, "CRON_TZ=") {
+		if i := strings.Index(spec, " "); i > 0 {
+			prefix, spec = spec[:i+1], strings.TrimSpace(spec[i:])
+		}
+	}
+
+	fields := strings.Fields(spec)
+	if strings.HasPrefix(spec, "@") || (len(fields) != 5 && len(fields) != 6) {
+		return parse(prefix + spec)
+	}
+
+	idom, idow := len(fields)-3, len(fields)-1
+	dom, dow := fields[idom], fields[idow]
+	if !hasOperator(dom, isDomOperator) && !hasOperator(dow, isDowOperator) {
+		return parse(prefix + spec)
+	}
+
+	fields[idom], fields[idow] = "*", "*"
+	schedule, err := parser.Parse(prefix + strings.Join(fields, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	s := &extended{
+		time:     withDST(schedule),
+		location: schedule.(*cron.SpecSchedule).Location,
+	}
+
+	s.dom, err = parseDays(dom, true)
+	if err != nil {
+		return nil, errors.Wrap(err, "day of month")
+	}
+
+	s.dow, err = parseDays(dow, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "day of week")
+	}
+
+	return s, nil
+}
+
+func parse(spec string) (cron.Schedule, error) {
+	schedule, err := parser.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	return withDST(schedule), nil
+}
+
+func (s *extended) Next(t time.Time) time.Time {
+	loc := s.location
+	if loc == time.Local {
+		loc = t.Location() // Same behavior as cron.SpecSchedule
+	}
+
+	// The time schedule matches every day, skip the days not matching the day operators.
+	for i := 0; i < 5*366; i++ {
+		next := s.time.Next(t)
+		if next.IsZero() {
+			return next
+		}
+
+		day := next.In(loc)
+		if s.matches(day) {
+			return next
+		}
+
+		t = time.Date(day.Year(), day.Month(), day.Day(), 23, 59, 59, 0, loc)
+	}
+
+	return time.Time{}
+}
+
+// matches returns true if the given day matches the day of month and the day of week restrictions.
+// Like crontab, the day matches either restrictions if both are restricted.
+func (s *extended) matches(t time.Time) bool {
+	dom := s.dom.matches(t, t.Day())
+	dow := s.dow.matches(t, int(t.Weekday()))
+
+	if s.dom.restricted && s.dow.restricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+func (d days) matches(t time.Time, bit int) bool {
+	if !d.restricted || d.bits&(1<<uint(bit)) != 0 {
+		return true
+	}
+
+	for _, operator := range d.operators {
+		if operator(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDays parses the items of the given day of month or day of week field.
+// The items without operator are parsed by the cron parser.
+func parseDays(field string, dom bool) (days, error) {
+	isOperator, operator, spec := isDowOperator, dowOperator, "0 0 0 * * %s"
+	if dom {
+		isOperator, operator, spec = isDomOperator, domOperator, "0 0 0 %s * *"
+	}
+
+	d := days{
+		restricted: field != "*" && field != "?",
+	}
+
+	var normal []string
+	for _, item := range strings.Split(field, ",") {
+		if !isOperator(item) {
+			normal = append(normal, item)
+			continue
+		}
+
+		fn, err := operator(strings.ToUpper(item))
+		if err != nil {
+			return d, err
+		}
+		d.operators = append(d.operators, fn)
+	}
+
+	if len(normal) == 0 {
+		return d, nil
+	}
+
+	schedule, err := parser.Parse(fmt.Sprintf(spec, strings.Join(normal, ",")))
+	if err != nil {
+		return d, err
+	}
+
+	d.bits = schedule.(*cron.SpecSchedule).Dow
+	if dom {
+		d.bits = schedule.(*cron.SpecSchedule).Dom
+	}
+	return d, nil
+}
+
+func hasOperator(field string, isOperator func(string) bool) bool {
+	for _, item := range strings.Split(field, ",") {
+		if isOperator(item) {
+			return true
+		}
+	}
+	return false
+}
+
+func isDomOperator(item string) bool {
+	item = strings.ToUpper(item)
+	return strings.HasPrefix(item, "L") || strings.HasSuffix(item, "W")
+}
+
+func isDowOperator(item string) bool {
+	item = strings.ToUpper(item)
+	return strings.Contains(item, "#") || (len(item) > 1 && strings.HasSuffix(item, "L"))
+}
+
+func domOperator(item string) (func(time.Time) bool, error) {
+	switch {
+	case item == "L":
+		return func(t time.Time) bool {
+			return t.Day() == lastDay(t)
+		}, nil
+	case item == "LW":
+		return func(t time.Time) bool {
+			return t.Day() == nearestWeekday(t, lastDay(t))
+		}, nil
+	case strings.HasPrefix(item, "L-"):
+		n, err := strconv.Atoi(item[2:])
+		if err != nil || n < 0 || n > 30 {
+			return nil, errors.Errorf("invalid offset '%s'", item)
+		}
+
+		return func(t time.Time) bool {
+			return t.Day() == lastDay(t)-n
+		}, nil
+	case strings.HasSuffix(item, "W"):
+		n, err := strconv.Atoi(item[:len(item)-1])
+		if err != nil || n < 1 || n > 31 {
+			return nil, errors.Errorf("invalid day '%s'", item)
+		}
+
+		return func(t time.Time) bool {
+			return n <= lastDay(t) && t.Day() == nearestWeekday(t, n)
+		}, nil
+	}
+
+	return nil, errors.Errorf("unsupported operator '%s'", item)
+}
+
+func dowOperator(item string) (func(time.Time) bool, error) {
+	if weekday, nth, ok := strings.Cut(item, "#"); ok {
+		wd, err := parseWeekday(weekday)
+		if err != nil {
+			return nil, err
+		}
+
+		n, err := strconv.Atoi(nth)
+		if err != nil || n < 1 || n > 5 {
+			return nil, errors.Errorf("invalid occurrence '%s'", item)
+		}
+
+		return func(t time.Time) bool {
+			return t.Weekday() == wd && (t.Day()-1)/7+1 == n
+		}, nil
+	}
+
+	wd, err := parseWeekday(strings.TrimSuffix(item, "L"))
+	if err != nil {
+		return nil, err
+	}
+
+	return func(t time.Time) bool {
+		return t.Weekday() == wd && t.Day()+7 > lastDay(t)
+	}, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	if wd, ok := weekdays[strings.ToLower(s)]; ok {
+		return time.Weekday(wd), nil
+	}
+
+	wd, err := strconv.Atoi(s)
+	if err != nil || wd < 0 || wd > 7 {
+		return 0, errors.Errorf("invalid weekday '%s'", s)
+	}
+	return time.Weekday(wd % 7), nil
+}
+
+// lastDay returns the last day of the month of t.
+func lastDay(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// nearestWeekday returns the weekday nearest to the given day of the month of t, without leaving the month.
+func nearestWeekday(t time.Time, day int) int {
+	switch time.Date(t.Year(), t.Month(), day, 0, 0, 0, 0, time.UTC).Weekday() {
+	case time.Saturday:
+		if day == 1 {
+			return day + 2
+		}
+		return day - 1
+	case time.Sunday:
+		if day == lastDay(t) {
+			return day - 2
+		}
+		return day + 1
+	}
+	return day
+}
@@ -0,0 +1,152 @@
+package shigoto
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleOperators(t *testing.T) {
+	utc := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name  string
+		spec  string
+		from  time.Time
+		fires []time.Time
+	}{
+		{
+			name:  "last day of month",
+			spec:  "0 0 L * *",
+			from:  utc(2028, 1, 15),
+			fires: []time.Time{utc(2028, 1, 31), utc(2028, 2, 29), utc(2028, 3, 31)},
+		},
+		{
+			name:  "third to last day of month",
+			spec:  "0 0 L-3 * *",
+			from:  utc(2026, 2, 1),
+			fires: []time.Time{utc(2026, 2, 25), utc(2026, 3, 28), utc(2026, 4, 27)},
+		},
+		{
+			name: "nearest weekday of the 15th",
+			spec: "0 0 15W * *",
+			from: utc(2026, 2, 1),
+			fires: []time.Time{
+				utc(2026, 2, 16), // Sunday 15th
+				utc(2026, 3, 16), // Sunday 15th
+				utc(2026, 4, 15),
+			},
+		},
+		{
+			name:  "nearest weekday of a Saturday 15th",
+			spec:  "0 0 15W * *",
+			from:  utc(2026, 8, 1),
+			fires: []time.Time{utc(2026, 8, 14)},
+		},
+		{
+			name: "nearest weekday of the 1st does not leave the month",
+			spec: "0 0 1W * *",
+			from: utc(2026, 7, 15),
+			fires: []time.Time{
+				utc(2026, 8, 3), // Saturday 1st, not Friday July 31st
+				utc(2026, 9, 1),
+				utc(2026, 10, 1),
+				utc(2026, 11, 2), // Sunday 1st
+			},
+		},
+		{
+			name: "nearest weekday of the 31st does not leave the month",
+			spec: "0 0 31W * *",
+			from: utc(2026, 5, 1),
+			fires: []time.Time{
+				utc(2026, 5, 29), // Sunday 31st, not Monday June 1st
+				utc(2026, 7, 31),
+				utc(2026, 8, 31),
+				utc(2026, 10, 30), // Saturday 31st
+				utc(2026, 12, 31),
+			},
+		},
+		{
+			name: "last weekday of month",
+			spec: "0 0 LW * *",
+			from: utc(2026, 1, 1),
+			fires: []time.Time{
+				utc(2026, 1, 30), // Saturday 31st
+				utc(2026, 2, 27), // Saturday 28th
+				utc(2026, 3, 31),
+			},
+		},
+		{
+			name:  "last Friday of month",
+			spec:  "0 0 * * 5L",
+			from:  utc(2026, 10, 1),
+			fires: []time.Time{utc(2026, 10, 30), utc(2026, 11, 27), utc(2026, 12, 25)},
+		},
+		{
+			name:  "second Wednesday of month",
+			spec:  "0 0 * * 3#2",
+			from:  utc(2026, 10, 1),
+			fires: []time.Time{utc(2026, 10, 14), utc(2026, 11, 11), utc(2026, 12, 9)},
+		},
+		{
+			name:  "named weekday occurrence",
+			spec:  "0 0 * * wed#2",
+			from:  utc(2026, 10, 1),
+			fires: []time.Time{utc(2026, 10, 14)},
+		},
+		{
+			name: "day of month or day of week",
+			spec: "0 0 1 * 5L",
+			from: utc(2026, 10, 2),
+			fires: []time.Time{
+				utc(2026, 10, 30),
+				utc(2026, 11, 1),
+				utc(2026, 11, 27),
+				utc(2026, 12, 1),
+			},
+		},
+		{
+			name: "operator with seconds field",
+			spec: "30 0 0 L * *",
+			from: utc(2026, 2, 1),
+			fires: []time.Time{
+				utc(2026, 2, 28).Add(30 * time.Second),
+				utc(2026, 3, 31).Add(30 * time.Second),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseSchedule("CRON_TZ=UTC " + tt.spec)
+			if err != nil {
+				t.Fatalf("parse %q: %v", tt.spec, err)
+			}
+
+			next := tt.from
+			for i, expected := range tt.fires {
+				next = schedule.Next(next)
+				if !next.Equal(expected) {
+					t.Fatalf("fire #%d: got %s, expected %s", i, next, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestParseScheduleInvalidOperators(t *testing.T) {
+	for _, spec := range []string{
+		"0 0 32W * *",
+		"0 0 0W * *",
+		"0 0 L-31 * *",
+		"0 0 * * 3#6",
+		"0 0 * * 3#0",
+		"0 0 * * 8L",
+		"0 0 * * foo#1",
+	} {
+		if _, err := parseSchedule(spec); err == nil {
+			t.Errorf("parse %q: expected an error", spec)
+		}
+	}
+}
@@ -2,6 +2,7 @@
 package templater
 
 import (
+	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -32,6 +33,10 @@ func init() {
 		"toSlash": func(path string) string {
 			return filepath.ToSlash(path)
 		},
+		"exists": func(path string) bool {
+			_, err := os.Stat(path)
+			return err == nil
+		},
 		"exeExt": func() string {
 			if runtime.GOOS == "windows" {
 				return ".exe"
@@ -16,6 +16,7 @@ type (
 		ReplaceSlice([]string) []string
 		ReplaceMap(map[string]string) map[string]string
 		ReplaceMapI(map[string]any) map[string]any
+		ReplaceAny(any) any
 		Err() error
 	}
 
@@ -24,9 +25,16 @@ type (
 		Variables() map[string]string
 	}
 
+	// A ValuesContext is a Context that also provides values that are not strings.
+	ValuesContext interface {
+		Context
+		Values() map[string]any
+	}
+
 	templater struct {
-		ctx Context
-		err error
+		ctx    Context
+		strict bool
+		err    error
 	}
 )
 
@@ -37,19 +45,38 @@ func New(ctx Context) Templater {
 	}
 }
 
+// NewStrict returns a new Templater failing on the missing keys instead of rendering `<no value>`.
+func NewStrict(ctx Context) Templater {
+	return &templater{
+		ctx:    ctx,
+		strict: true,
+	}
+}
+
+// Parse checks the syntax of the given template.
+func Parse(str string) error {
+	_, err := template.New("").Funcs(templateFuncs).Parse(str)
+	return err
+}
+
 func (r *templater) Replace(str string) string {
 	if r.err != nil || str == "" {
 		return ""
 	}
 
-	templ, err := template.New("").Funcs(templateFuncs).Parse(str)
+	templ := template.New("").Funcs(templateFuncs)
+	if r.strict {
+		templ = templ.Option("missingkey=error")
+	}
+
+	templ, err := templ.Parse(str)
 	if err != nil {
 		r.err = err
 		return ""
 	}
 
 	var b bytes.Buffer
-	if err = templ.Execute(&b, r.ctx.Variables()); err != nil {
+	if err = templ.Execute(&b, r.data()); err != nil {
 		r.err = err
 		return ""
 	}
@@ -96,6 +123,50 @@ func (r *templater) ReplaceMapI(m map[string]any) map[string]any {
 	return new
 }
 
+// ReplaceAny replaces the given string or the strings nested in the given maps and lists.
+// The other values are returned as is.
+func (r *templater) ReplaceAny(v any) any {
+	if r.err != nil {
+		return nil
+	}
+
+	switch v := v.(type) {
+	case string:
+		return r.Replace(v)
+	case map[string]any:
+		new := make(map[string]any, len(v))
+		for k, v := range v {
+			new[k] = r.ReplaceAny(v)
+		}
+		return new
+	case []any:
+		new := make([]any, len(v))
+		for i, v := range v {
+			new[i] = r.ReplaceAny(v)
+		}
+		return new
+	default:
+		return v
+	}
+}
+
+// data returns the data used to execute the templates.
+func (r *templater) data() any {
+	ctx, ok := r.ctx.(ValuesContext)
+	if !ok {
+		return r.ctx.Variables()
+	}
+
+	data := make(map[string]any)
+	for k, v := range r.ctx.Variables() {
+		data[k] = v
+	}
+	for k, v := range ctx.Values() {
+		data[k] = v
+	}
+	return data
+}
+
 func (r *templater) Err() error {
 	return r.err
 }